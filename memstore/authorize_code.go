@@ -0,0 +1,81 @@
+package memstore
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"time"
+)
+
+var (
+	_ oauth.ConsumableAuthorizeCodeRepository = (*AuthorizeCodeRepository)(nil)
+	_ Sweepable                               = (*AuthorizeCodeRepository)(nil)
+)
+
+// Default time to live of authorization codes.
+const DefaultAuthorizeCodeTTL = 10 * time.Minute
+
+// In-memory oauth.AuthorizeCodeRepository, which supports replay detection. Codes expire after TTL, consumed or not.
+// Requests are snapshot with Requests, as done by AccessTokenRepository.
+type AuthorizeCodeRepository struct {
+	TTL      time.Duration
+	Requests *oauth.RequestCodec
+	table    *table
+}
+
+type authorizeCodeEntry struct {
+	req      oauth.AuthorizeRequest
+	consumer string
+}
+
+// Create a new AuthorizeCodeRepository whose codes expire after ttl. A zero ttl defaults to DefaultAuthorizeCodeTTL.
+func NewAuthorizeCodeRepository(ttl time.Duration) *AuthorizeCodeRepository {
+	if ttl == 0 {
+		ttl = DefaultAuthorizeCodeTTL
+	}
+	return &AuthorizeCodeRepository{TTL: ttl, table: newTable()}
+}
+
+func (r *AuthorizeCodeRepository) GetRequest(ctx context.Context, code string) (oauth.AuthorizeRequest, error) {
+	v, ok := r.table.get(code)
+	if !ok {
+		return nil, spi.ErrInvalidGrant("authorization code is unknown or expired.")
+	}
+
+	req, err := snapshot(r.Requests, v.(*authorizeCodeEntry).req)
+	if err != nil {
+		return nil, spi.AsOAuthError(err)
+	}
+	return req.(oauth.AuthorizeRequest), nil
+}
+
+func (r *AuthorizeCodeRepository) Save(ctx context.Context, code string, req oauth.AuthorizeRequest) error {
+	stored, err := snapshot(r.Requests, req)
+	if err != nil {
+		return spi.AsOAuthError(err)
+	}
+	r.table.put(code, req.GetId(), &authorizeCodeEntry{req: stored.(oauth.AuthorizeRequest)}, r.TTL)
+	return nil
+}
+
+func (r *AuthorizeCodeRepository) Delete(ctx context.Context, code string) error {
+	r.table.remove(code)
+	return nil
+}
+
+func (r *AuthorizeCodeRepository) MarkConsumed(ctx context.Context, code string, requestId string) (string, error) {
+	var previous string
+	if !r.table.update(code, func(value interface{}) {
+		e := value.(*authorizeCodeEntry)
+		if previous = e.consumer; len(previous) == 0 {
+			e.consumer = requestId
+		}
+	}) {
+		return "", spi.ErrInvalidGrant("authorization code is unknown or expired.")
+	}
+	return previous, nil
+}
+
+func (r *AuthorizeCodeRepository) Sweep(now time.Time) int {
+	return r.table.sweep(now)
+}
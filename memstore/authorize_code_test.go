@@ -0,0 +1,46 @@
+package memstore
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+func TestAuthorizeCodeRepository(t *testing.T) {
+	s := new(AuthorizeCodeRepositoryTestSuite)
+	suite.Run(t, s)
+}
+
+type AuthorizeCodeRepositoryTestSuite struct {
+	suite.Suite
+	repo *AuthorizeCodeRepository
+}
+
+func (s *AuthorizeCodeRepositoryTestSuite) SetupTest() {
+	s.repo = NewAuthorizeCodeRepository(0)
+}
+
+func (s *AuthorizeCodeRepositoryTestSuite) TestMarkConsumed() {
+	ctx := context.Background()
+	req := oauth.NewAuthorizeRequest()
+	s.Require().Nil(s.repo.Save(ctx, "code", req))
+
+	previous, err := s.repo.MarkConsumed(ctx, "code", "first")
+	s.Assert().Nil(err)
+	s.Assert().Empty(previous)
+
+	previous, err = s.repo.MarkConsumed(ctx, "code", "second")
+	s.Assert().Nil(err)
+	s.Assert().Equal("first", previous)
+
+	// consumed codes remain retrievable for replay detection
+	found, err := s.repo.GetRequest(ctx, "code")
+	s.Assert().Nil(err)
+	s.Assert().Equal(req.GetId(), found.GetId())
+}
+
+func (s *AuthorizeCodeRepositoryTestSuite) TestMarkConsumedUnknownCode() {
+	_, err := s.repo.MarkConsumed(context.Background(), "unknown", "first")
+	s.Assert().NotNil(err)
+}
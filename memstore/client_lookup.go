@@ -0,0 +1,49 @@
+package memstore
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/spi"
+	"sync"
+)
+
+var (
+	_ spi.ClientLookup = (*ClientLookup)(nil)
+)
+
+// In-memory spi.ClientLookup. Clients never expire, they remain registered until removed.
+type ClientLookup struct {
+	mu      sync.RWMutex
+	clients map[string]spi.OAuthClient
+}
+
+// Create a new ClientLookup with the clients registered.
+func NewClientLookup(clients ...spi.OAuthClient) *ClientLookup {
+	l := &ClientLookup{clients: make(map[string]spi.OAuthClient)}
+	for _, client := range clients {
+		l.Register(client)
+	}
+	return l
+}
+
+// Register the client, replacing any client registered with the same id.
+func (l *ClientLookup) Register(client spi.OAuthClient) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clients[client.GetId()] = client
+}
+
+// Remove the client with the id, if registered.
+func (l *ClientLookup) Remove(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.clients, id)
+}
+
+func (l *ClientLookup) FindById(ctx context.Context, id string) (spi.OAuthClient, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if client, ok := l.clients[id]; ok {
+		return client, nil
+	}
+	return nil, spi.ErrInvalidClient("client is not registered.", "")
+}
@@ -0,0 +1,51 @@
+package memstore
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"time"
+)
+
+var (
+	_ oauth.RefreshTokenRotationRepository = (*RefreshTokenRotationRepository)(nil)
+	_ Sweepable                            = (*RefreshTokenRotationRepository)(nil)
+)
+
+// In-memory oauth.RefreshTokenRotationRepository. Rotations expire after TTL, which should be no shorter than the time
+// to live of refresh tokens, otherwise reuse of a rotated token can no longer be detected.
+type RefreshTokenRotationRepository struct {
+	TTL   time.Duration
+	table *table
+}
+
+// Create a new RefreshTokenRotationRepository whose rotations expire after ttl. A zero ttl defaults to
+// DefaultRefreshTokenTTL.
+func NewRefreshTokenRotationRepository(ttl time.Duration) *RefreshTokenRotationRepository {
+	if ttl == 0 {
+		ttl = DefaultRefreshTokenTTL
+	}
+	return &RefreshTokenRotationRepository{TTL: ttl, table: newTable()}
+}
+
+func (r *RefreshTokenRotationRepository) SaveRotation(ctx context.Context, rotation *oauth.RefreshTokenRotation) (
+	[]*oauth.RefreshTokenRotation, error) {
+	key := rotation.ParentRequestId + "/" + rotation.ChildRequestId
+	return toRotations(r.table.putAndFindByRequestId(key, rotation.ParentRequestId, rotation, r.TTL)), nil
+}
+
+func (r *RefreshTokenRotationRepository) GetRotations(ctx context.Context, parentRequestId string) (
+	[]*oauth.RefreshTokenRotation, error) {
+	return toRotations(r.table.findByRequestId(parentRequestId)), nil
+}
+
+func toRotations(values []interface{}) []*oauth.RefreshTokenRotation {
+	rotations := make([]*oauth.RefreshTokenRotation, 0, len(values))
+	for _, v := range values {
+		rotations = append(rotations, v.(*oauth.RefreshTokenRotation))
+	}
+	return rotations
+}
+
+func (r *RefreshTokenRotationRepository) Sweep(now time.Time) int {
+	return r.table.sweep(now)
+}
@@ -0,0 +1,41 @@
+package memstore
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRefreshTokenRotationRepository_ConcurrentSave(t *testing.T) {
+	repo := NewRefreshTokenRotationRepository(0)
+
+	wg := new(sync.WaitGroup)
+	unseen := make(chan string, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			previous, err := repo.SaveRotation(context.Background(), &oauth.RefreshTokenRotation{
+				ParentRequestId: "parent",
+				ChildRequestId:  string(rune('a' + i)),
+				RotatedAt:       time.Now(),
+			})
+			assert.Nil(t, err)
+			if len(previous) == 0 {
+				unseen <- string(rune('a' + i))
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(unseen)
+
+	// only the first rotation is unaware of the others
+	assert.Len(t, unseen, 1)
+
+	rotations, err := repo.GetRotations(context.Background(), "parent")
+	assert.Nil(t, err)
+	assert.Len(t, rotations, 10)
+}
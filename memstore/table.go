@@ -0,0 +1,196 @@
+// Package memstore provides concurrency-safe in-memory implementations of the repositories required by the SDK. Entries
+// expire after a time to live, and are swept in the background by StartSweeper. The implementations are suitable for
+// integration tests and small, single instance deployments.
+//
+// Requests are stored as snapshots taken with an oauth.RequestCodec, so that neither the caller of Save nor the callers
+// of GetRequest share mutable state with the repository or with one another.
+package memstore
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"sync"
+	"time"
+)
+
+// Codec used by repositories whose Requests is nil, which knows the implementations of the oauth package only.
+var defaultRequests = oauth.NewRequestCodec(nil)
+
+// Returns a snapshot of the request taken with the codec, or the default codec if nil.
+func snapshot(codec *oauth.RequestCodec, req oauth.Request) (oauth.Request, error) {
+	if codec == nil {
+		codec = defaultRequests
+	}
+	return codec.Clone(req)
+}
+
+// Implemented by all repositories of this package.
+type Sweepable interface {
+	// Remove entries which expired by the given time. Returns the number of entries removed.
+	Sweep(now time.Time) int
+}
+
+// Sweep expired entries from the repositories every interval until the context is cancelled.
+func StartSweeper(ctx context.Context, interval time.Duration, repos ...Sweepable) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				for _, repo := range repos {
+					repo.Sweep(now)
+				}
+			}
+		}
+	}()
+}
+
+type entry struct {
+	value     interface{}
+	requestId string
+	expiresAt time.Time
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// internal map of expiring entries, with a secondary index on the request id.
+type table struct {
+	mu        sync.RWMutex
+	entries   map[string]*entry
+	byRequest map[string]map[string]struct{}
+}
+
+func newTable() *table {
+	return &table{
+		entries:   make(map[string]*entry),
+		byRequest: make(map[string]map[string]struct{}),
+	}
+}
+
+// Put the value under the key. A zero ttl never expires.
+func (t *table) put(key string, requestId string, value interface{}, ttl time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.putLocked(key, requestId, value, ttl)
+}
+
+// Put the value under the key, and return the values indexed under the request id before, which are not expired, as one
+// atomic operation.
+func (t *table) putAndFindByRequestId(key string, requestId string, value interface{}, ttl time.Duration) []interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.removeLocked(key)
+	values := t.findByRequestIdLocked(requestId)
+	t.putLocked(key, requestId, value, ttl)
+	return values
+}
+
+func (t *table) putLocked(key string, requestId string, value interface{}, ttl time.Duration) {
+	t.removeLocked(key)
+
+	e := &entry{value: value, requestId: requestId}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+	t.entries[key] = e
+
+	if len(requestId) > 0 {
+		if _, ok := t.byRequest[requestId]; !ok {
+			t.byRequest[requestId] = make(map[string]struct{})
+		}
+		t.byRequest[requestId][key] = struct{}{}
+	}
+}
+
+// Returns the value under the key, if present and not expired.
+func (t *table) get(key string) (interface{}, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	e, ok := t.entries[key]
+	if !ok || e.expired(time.Now()) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Apply the update to the value under the key while holding the write lock. Returns false if the key is absent or
+// expired, in which case update is not called.
+func (t *table) update(key string, update func(value interface{})) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[key]
+	if !ok || e.expired(time.Now()) {
+		return false
+	}
+	update(e.value)
+	return true
+}
+
+func (t *table) remove(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.removeLocked(key)
+}
+
+func (t *table) removeByRequestId(requestId string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.byRequest[requestId] {
+		t.removeLocked(key)
+	}
+}
+
+// Returns the values indexed under the request id, which are not expired.
+func (t *table) findByRequestId(requestId string) []interface{} {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.findByRequestIdLocked(requestId)
+}
+
+func (t *table) findByRequestIdLocked(requestId string) []interface{} {
+	now := time.Now()
+	values := make([]interface{}, 0, len(t.byRequest[requestId]))
+	for key := range t.byRequest[requestId] {
+		if e := t.entries[key]; !e.expired(now) {
+			values = append(values, e.value)
+		}
+	}
+	return values
+}
+
+func (t *table) sweep(now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	count := 0
+	for key, e := range t.entries {
+		if e.expired(now) {
+			t.removeLocked(key)
+			count++
+		}
+	}
+	return count
+}
+
+func (t *table) removeLocked(key string) {
+	e, ok := t.entries[key]
+	if !ok {
+		return
+	}
+	delete(t.entries, key)
+
+	if keys, ok := t.byRequest[e.requestId]; ok {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(t.byRequest, e.requestId)
+		}
+	}
+}
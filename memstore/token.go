@@ -0,0 +1,125 @@
+package memstore
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"time"
+)
+
+var (
+	_ oauth.AccessTokenRepository  = (*AccessTokenRepository)(nil)
+	_ oauth.RefreshTokenRepository = (*RefreshTokenRepository)(nil)
+	_ Sweepable                    = (*AccessTokenRepository)(nil)
+	_ Sweepable                    = (*RefreshTokenRepository)(nil)
+)
+
+const (
+	// Default time to live of access tokens.
+	DefaultAccessTokenTTL = 1 * time.Hour
+	// Default time to live of refresh tokens.
+	DefaultRefreshTokenTTL = 14 * 24 * time.Hour
+)
+
+// In-memory oauth.AccessTokenRepository. Tokens expire after TTL, which should be no shorter than the lifespan
+// configured on the oauth.AccessTokenHelper. Tokens are indexed by the id of the request they were issued to.
+//
+// Requests are snapshot with Requests, which defaults to a codec knowing the oauth implementations only. Open ID
+// Connect deployments should set a codec with the oidc types registered by oidc.RegisterCodecTypes.
+type AccessTokenRepository struct {
+	TTL      time.Duration
+	Requests *oauth.RequestCodec
+	table    *table
+}
+
+// Create a new AccessTokenRepository whose tokens expire after ttl. A zero ttl defaults to DefaultAccessTokenTTL.
+func NewAccessTokenRepository(ttl time.Duration) *AccessTokenRepository {
+	if ttl == 0 {
+		ttl = DefaultAccessTokenTTL
+	}
+	return &AccessTokenRepository{TTL: ttl, table: newTable()}
+}
+
+func (r *AccessTokenRepository) Save(ctx context.Context, token string, req oauth.Request) error {
+	stored, err := snapshot(r.Requests, req)
+	if err != nil {
+		return spi.AsOAuthError(err)
+	}
+	r.table.put(token, req.GetId(), stored, r.TTL)
+	return nil
+}
+
+func (r *AccessTokenRepository) GetRequest(ctx context.Context, token string) (oauth.Request, error) {
+	if v, ok := r.table.get(token); ok {
+		req, err := snapshot(r.Requests, v.(oauth.Request))
+		if err != nil {
+			return nil, spi.AsOAuthError(err)
+		}
+		return req, nil
+	}
+	return nil, spi.ErrInvalidToken("access token is unknown or expired.")
+}
+
+func (r *AccessTokenRepository) Delete(ctx context.Context, token string) error {
+	r.table.remove(token)
+	return nil
+}
+
+func (r *AccessTokenRepository) DeleteByRequestId(ctx context.Context, requestId string) error {
+	r.table.removeByRequestId(requestId)
+	return nil
+}
+
+func (r *AccessTokenRepository) Sweep(now time.Time) int {
+	return r.table.sweep(now)
+}
+
+// In-memory oauth.RefreshTokenRepository. Tokens expire after TTL and are indexed by the id of the request they were
+// issued to. Requests are snapshot with Requests, as done by AccessTokenRepository.
+type RefreshTokenRepository struct {
+	TTL      time.Duration
+	Requests *oauth.RequestCodec
+	table    *table
+}
+
+// Create a new RefreshTokenRepository whose tokens expire after ttl. A zero ttl defaults to DefaultRefreshTokenTTL.
+func NewRefreshTokenRepository(ttl time.Duration) *RefreshTokenRepository {
+	if ttl == 0 {
+		ttl = DefaultRefreshTokenTTL
+	}
+	return &RefreshTokenRepository{TTL: ttl, table: newTable()}
+}
+
+func (r *RefreshTokenRepository) Save(ctx context.Context, token string, req oauth.Request) error {
+	stored, err := snapshot(r.Requests, req)
+	if err != nil {
+		return spi.AsOAuthError(err)
+	}
+	r.table.put(token, req.GetId(), stored, r.TTL)
+	return nil
+}
+
+func (r *RefreshTokenRepository) GetRequest(ctx context.Context, token string) (oauth.Request, error) {
+	if v, ok := r.table.get(token); ok {
+		req, err := snapshot(r.Requests, v.(oauth.Request))
+		if err != nil {
+			return nil, spi.AsOAuthError(err)
+		}
+		return req, nil
+	}
+	return nil, spi.ErrInvalidGrant("refresh token is unknown or expired.")
+}
+
+func (r *RefreshTokenRepository) Delete(ctx context.Context, token string) error {
+	r.table.remove(token)
+	return nil
+}
+
+func (r *RefreshTokenRepository) DeleteByRequestId(ctx context.Context, requestId string) error {
+	r.table.removeByRequestId(requestId)
+	return nil
+}
+
+func (r *RefreshTokenRepository) Sweep(now time.Time) int {
+	return r.table.sweep(now)
+}
@@ -0,0 +1,132 @@
+package memstore
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/stretchr/testify/suite"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTokenRepository(t *testing.T) {
+	s := new(TokenRepositoryTestSuite)
+	suite.Run(t, s)
+}
+
+type TokenRepositoryTestSuite struct {
+	suite.Suite
+}
+
+func (s *TokenRepositoryTestSuite) TestDeleteByRequestId() {
+	repo := NewAccessTokenRepository(0)
+	ctx := context.Background()
+
+	req1, req2 := oauth.NewTokenRequest(), oauth.NewTokenRequest()
+	s.Require().Nil(repo.Save(ctx, "a", req1))
+	s.Require().Nil(repo.Save(ctx, "b", req1))
+	s.Require().Nil(repo.Save(ctx, "c", req2))
+
+	s.Require().Nil(repo.DeleteByRequestId(ctx, req1.GetId()))
+
+	for _, v := range []struct {
+		token  string
+		exists bool
+	}{
+		{token: "a", exists: false},
+		{token: "b", exists: false},
+		{token: "c", exists: true},
+	} {
+		req, err := repo.GetRequest(ctx, v.token)
+		if v.exists {
+			s.Assert().Nil(err, v.token)
+			s.Assert().Equal(req2.GetId(), req.GetId(), v.token)
+		} else {
+			s.Assert().NotNil(err, v.token)
+		}
+	}
+}
+
+func (s *TokenRepositoryTestSuite) TestExpiry() {
+	repo := NewRefreshTokenRepository(50 * time.Millisecond)
+	ctx := context.Background()
+	req := oauth.NewTokenRequest()
+
+	s.Require().Nil(repo.Save(ctx, "a", req))
+	_, err := repo.GetRequest(ctx, "a")
+	s.Assert().Nil(err)
+
+	time.Sleep(60 * time.Millisecond)
+
+	// expired entries are invisible even before being swept
+	_, err = repo.GetRequest(ctx, "a")
+	s.Assert().NotNil(err)
+	s.Assert().Equal(1, repo.Sweep(time.Now()))
+	s.Assert().Equal(0, repo.Sweep(time.Now()))
+	s.Assert().Empty(repo.table.byRequest)
+}
+
+func (s *TokenRepositoryTestSuite) TestSweeper() {
+	repo := NewAccessTokenRepository(10 * time.Millisecond)
+	s.Require().Nil(repo.Save(context.Background(), "a", oauth.NewTokenRequest()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	StartSweeper(ctx, 20*time.Millisecond, repo)
+
+	time.Sleep(60 * time.Millisecond)
+	repo.table.mu.RLock()
+	defer repo.table.mu.RUnlock()
+	s.Assert().Empty(repo.table.entries)
+}
+
+func (s *TokenRepositoryTestSuite) TestConcurrentAccess() {
+	repo := NewAccessTokenRepository(0)
+	ctx := context.Background()
+	req := oauth.NewTokenRequest()
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			token := string(rune('A' + i))
+			_ = repo.Save(ctx, token, req)
+			_, _ = repo.GetRequest(ctx, token)
+			_ = repo.DeleteByRequestId(ctx, req.GetId())
+		}(i)
+	}
+	wg.Wait()
+
+	s.Assert().Nil(repo.DeleteByRequestId(ctx, req.GetId()))
+	s.Assert().Empty(repo.table.entries)
+	s.Assert().Empty(repo.table.byRequest)
+}
+
+func (s *TokenRepositoryTestSuite) TestSnapshot() {
+	repo := NewAccessTokenRepository(0)
+	ctx := context.Background()
+	req := oauth.NewTokenRequest()
+	req.GetSession().SetSubject("test user")
+
+	s.Require().Nil(repo.Save(ctx, "a", req))
+	req.GetSession().SetSubject("changed after save")
+
+	// readers mutating their copy neither race with nor affect one another
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, err := repo.GetRequest(ctx, "a")
+			s.Assert().Nil(err)
+			s.Assert().Equal("test user", found.GetSession().GetSubject())
+			found.GetSession().SetSubject("changed by reader")
+		}()
+	}
+	wg.Wait()
+
+	found, err := repo.GetRequest(ctx, "a")
+	s.Require().Nil(err)
+	s.Assert().Equal("test user", found.GetSession().GetSubject())
+}
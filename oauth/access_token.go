@@ -38,12 +38,16 @@ func NewRs256JwtAccessTokenStrategy(
 	}
 }
 
+// AccessTokenStrategy which issues signed JWT access tokens. Tokens are signed with the key identified by KeyId in Jwks,
+// unless a KeySource (i.e. KeyManager) is set, in which case tokens are signed with its active key and verified with the
+// key identified by the kid header of the token.
 type JwtAccessTokenStrategy struct {
 	Issuer        string
 	TokenLifespan time.Duration
 	SigningAlg    jose.SignatureAlgorithm
 	Jwks    *jose.JSONWebKeySet
 	KeyId   string
+	KeySource SigningKeySource
 	_signer jose.Signer
 }
 
@@ -62,7 +66,12 @@ func (s *JwtAccessTokenStrategy) ComputeIdentifier(token string) (string, error)
 }
 
 func (s *JwtAccessTokenStrategy) NewToken(ctx context.Context, req Request) (string, error) {
-	b := jwt.Signed(s.mustSigner())
+	signer, err := s.signer()
+	if err != nil {
+		return "", err
+	}
+
+	b := jwt.Signed(signer)
 	b = b.Claims(&jwt.Claims{
 		ID:        uuid.NewV4().String(),
 		Issuer:    s.Issuer,
@@ -88,7 +97,9 @@ func (s *JwtAccessTokenStrategy) ValidateToken(ctx context.Context, token string
 
 	if tok, err := jwt.ParseSigned(token); err != nil {
 		return err
-	} else if err := tok.Claims(FindVerificationKeyById(s.Jwks, s.KeyId), &out); err != nil {
+	} else if key, err := s.verificationKey(tok); err != nil {
+		return err
+	} else if err := tok.Claims(key, &out); err != nil {
 		return err
 	} else if err := out.ValidateWithLeeway(jwt.Expected{
 		Issuer:   s.Issuer,
@@ -100,6 +111,46 @@ func (s *JwtAccessTokenStrategy) ValidateToken(ctx context.Context, token string
 	return nil
 }
 
+// Returns a signer with the active key of KeySource if set, or the cached signer with KeyId otherwise.
+func (s *JwtAccessTokenStrategy) signer() (jose.Signer, error) {
+	if s.KeySource == nil {
+		return s.mustSigner(), nil
+	}
+
+	key := s.KeySource.SigningKey(string(s.SigningAlg))
+	if key == nil {
+		return nil, spi.ErrServerErrorf("no active key to sign access token with %s", s.SigningAlg)
+	}
+
+	if signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: s.SigningAlg,
+		Key:       key,
+	}, (&jose.SignerOptions{}).WithType("JWT")); err != nil {
+		return nil, spi.ErrServerError(err)
+	} else {
+		return signer, nil
+	}
+}
+
+// Returns the key to verify the token with: the key identified by the kid header from KeySource if set, or the key
+// identified by KeyId otherwise.
+func (s *JwtAccessTokenStrategy) verificationKey(tok *jwt.JSONWebToken) (*jose.JSONWebKey, error) {
+	if s.KeySource == nil {
+		return FindVerificationKeyById(s.Jwks, s.KeyId), nil
+	}
+
+	if len(tok.Headers) == 0 || len(tok.Headers[0].KeyID) == 0 {
+		return nil, spi.ErrInvalidGrant("access token has no kid header.")
+	}
+
+	key := s.KeySource.VerificationKey(tok.Headers[0].KeyID)
+	if key == nil {
+		return nil, spi.ErrInvalidGrant("access token was signed by an unknown or retired key.")
+	}
+
+	return key, nil
+}
+
 func (s *JwtAccessTokenStrategy) mustSigner() jose.Signer {
 	if s._signer != nil {
 		return s._signer
@@ -123,22 +174,30 @@ type AccessTokenHelper struct {
 	Strategy AccessTokenStrategy
 	Repo     AccessTokenRepository
 	Lifespan time.Duration
+	// Determines whether the token is saved before or after being returned. Defaults to PersistAsync.
+	Mode PersistenceMode
 }
 
 func (h *AccessTokenHelper) GenToken(ctx context.Context, req Request, resp Response) error {
 	if tok, err := h.Strategy.NewToken(ctx, req); err != nil {
 		return err
 	} else {
-		go func() {
-			if err := h.Repo.Save(context.Background(), tok, req); err != nil {
-				logrus.WithFields(logrus.Fields{
-					"error":      err,
-					"token":      tok,
-					"request_id": req.GetId(),
-					"client_id":  req.GetClient().GetId(),
-				}).Errorln("failed to save access token.")
+		if h.Mode == PersistSync {
+			if err := h.Repo.Save(ctx, tok, req); err != nil {
+				return spi.AsOAuthError(err)
 			}
-		}()
+		} else {
+			go func() {
+				if err := h.Repo.Save(context.Background(), tok, req); err != nil {
+					logrus.WithFields(logrus.Fields{
+						"error":      err,
+						"token":      tok,
+						"request_id": req.GetId(),
+						"client_id":  req.GetClient().GetId(),
+					}).Errorln("failed to save access token.")
+				}
+			}()
+		}
 		resp.Set(AccessToken, tok)
 		resp.Set(TokenType, "Bearer")
 		resp.Set(ExpiresIn, h.Lifespan.Nanoseconds() / int64(time.Second))
@@ -29,6 +29,19 @@ type AuthorizeCodeRepository interface {
 	Delete(ctx context.Context, code string) error
 }
 
+// Add-on interface for AuthorizeCodeRepository to implement if it supports replay detection. Codes are marked as consumed
+// upon redemption instead of being deleted, so that a second redemption can be detected and the tokens issued by the
+// first redemption revoked, as recommended by RFC 6749 Section 4.1.2.
+//
+// GetRequest must keep returning the request of a consumed code until the code expires. Implementations are expected
+// to remove consumed codes along with expired ones.
+type ConsumableAuthorizeCodeRepository interface {
+	AuthorizeCodeRepository
+	// Atomically mark the code as consumed by the token request with the given id. Returns the id of the token request
+	// which had consumed the code before, or empty string if this is the first redemption.
+	MarkConsumed(ctx context.Context, code string, requestId string) (string, error)
+}
+
 func NewHmacShaAuthorizeCodeStrategy(entropy uint, hmac crypt.HmacShaStrategy) AuthorizeCodeStrategy {
 	return &hmacShaAuthorizeCodeStrategy{entropy: entropy, hmac: hmac}
 }
@@ -0,0 +1,195 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/imulab-z/platform-sdk/spi"
+	"reflect"
+)
+
+const (
+	// Type discriminators of the request and session implementations of this package.
+	CodecTypeRequest          = "oauth.request"
+	CodecTypeAuthorizeRequest = "oauth.authorize_request"
+	CodecTypeTokenRequest     = "oauth.token_request"
+	CodecTypeSession          = "oauth.session"
+)
+
+// Registry of request and session implementations, which serializes requests as JSON and back.
+//
+// Neither the client nor the session is serialized as part of the request, since they are interfaces. Instead, the
+// request is wrapped in an envelope carrying the type discriminator of the request, the client id, and the session
+// along with its own type discriminator. Upon decoding, the request and session are created by the factories
+// registered under their discriminators, and the client is looked up by id with Clients.
+//
+// Implementations of this package are registered by NewRequestCodec. Other implementations, like those of the oidc
+// package, must be registered before use. Registration is not safe for concurrent use with encoding and decoding.
+type RequestCodec struct {
+	Clients      spi.ClientLookup
+	requests     map[string]func() Request
+	sessions     map[string]func() Session
+	requestTypes map[reflect.Type]string
+	sessionTypes map[reflect.Type]string
+}
+
+type requestEnvelope struct {
+	Type        string          `json:"type"`
+	ClientId    string          `json:"client_id,omitempty"`
+	Request     json.RawMessage `json:"request"`
+	SessionType string          `json:"session_type,omitempty"`
+	Session     json.RawMessage `json:"session,omitempty"`
+}
+
+// Create a new RequestCodec with the implementations of this package registered.
+func NewRequestCodec(clients spi.ClientLookup) *RequestCodec {
+	c := &RequestCodec{
+		Clients:      clients,
+		requests:     make(map[string]func() Request),
+		sessions:     make(map[string]func() Session),
+		requestTypes: make(map[reflect.Type]string),
+		sessionTypes: make(map[reflect.Type]string),
+	}
+	c.RegisterRequest(CodecTypeRequest, NewRequest)
+	c.RegisterRequest(CodecTypeAuthorizeRequest, func() Request { return NewAuthorizeRequest() })
+	c.RegisterRequest(CodecTypeTokenRequest, func() Request { return NewTokenRequest() })
+	c.RegisterSession(CodecTypeSession, NewSession)
+	return c
+}
+
+// Register the request implementation created by the factory under the type discriminator.
+func (c *RequestCodec) RegisterRequest(name string, factory func() Request) {
+	c.requests[name] = factory
+	c.requestTypes[reflect.TypeOf(factory())] = name
+}
+
+// Register the session implementation created by the factory under the type discriminator.
+func (c *RequestCodec) RegisterSession(name string, factory func() Session) {
+	c.sessions[name] = factory
+	c.sessionTypes[reflect.TypeOf(factory())] = name
+}
+
+// Returns the type discriminator of the request implementation, if registered.
+func (c *RequestCodec) TypeOf(req Request) (string, bool) {
+	name, ok := c.requestTypes[reflect.TypeOf(req)]
+	return name, ok
+}
+
+// Serialize the request, which must be of a registered implementation, as must its session.
+func (c *RequestCodec) Encode(req Request) ([]byte, error) {
+	var (
+		envelope = requestEnvelope{}
+		ok       bool
+		err      error
+	)
+
+	if envelope.Type, ok = c.TypeOf(req); !ok {
+		return nil, fmt.Errorf("request type %T is not registered", req)
+	}
+
+	if client := req.GetClient(); client != nil {
+		envelope.ClientId = client.GetId()
+	}
+
+	if envelope.Request, err = json.Marshal(req); err != nil {
+		return nil, err
+	}
+
+	if session := req.GetSession(); session != nil {
+		if envelope.SessionType, ok = c.sessionTypes[reflect.TypeOf(session)]; !ok {
+			return nil, fmt.Errorf("session type %T is not registered", session)
+		}
+		if envelope.Session, err = json.Marshal(session); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(envelope)
+}
+
+// Deserialize the request, with its session restored and its client looked up.
+func (c *RequestCodec) Decode(ctx context.Context, data []byte) (Request, error) {
+	req, envelope, err := c.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(envelope.ClientId) > 0 {
+		client, err := c.Clients.FindById(ctx, envelope.ClientId)
+		if err != nil {
+			return nil, err
+		}
+		if err := attach(func() { req.SetClient(client) }); err != nil {
+			return nil, fmt.Errorf("client %s is not accepted by request type %s", envelope.ClientId, envelope.Type)
+		}
+	}
+
+	return req, nil
+}
+
+// Returns a deep copy of the request, which must be of a registered implementation, as must its session. The copy
+// shares no state with the original but the client, which is attached as is instead of being looked up.
+func (c *RequestCodec) Clone(req Request) (Request, error) {
+	data, err := c.Encode(req)
+	if err != nil {
+		return nil, err
+	}
+
+	clone, _, err := c.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if client := req.GetClient(); client != nil {
+		clone.SetClient(client)
+	}
+
+	return clone, nil
+}
+
+// Deserialize the request with its session restored, but without client.
+func (c *RequestCodec) decode(data []byte) (Request, *requestEnvelope, error) {
+	envelope := new(requestEnvelope)
+	if err := json.Unmarshal(data, envelope); err != nil {
+		return nil, nil, err
+	}
+
+	newRequest, ok := c.requests[envelope.Type]
+	if !ok {
+		return nil, nil, fmt.Errorf("request type %s is not registered", envelope.Type)
+	}
+
+	req := newRequest()
+	if err := json.Unmarshal(envelope.Request, req); err != nil {
+		return nil, nil, err
+	}
+
+	if len(envelope.SessionType) > 0 {
+		newSession, ok := c.sessions[envelope.SessionType]
+		if !ok {
+			return nil, nil, fmt.Errorf("session type %s is not registered", envelope.SessionType)
+		}
+
+		session := newSession()
+		if err := json.Unmarshal(envelope.Session, session); err != nil {
+			return nil, nil, err
+		}
+		if err := attach(func() { req.SetSession(session) }); err != nil {
+			return nil, nil, fmt.Errorf("session type %s is not accepted by request type %s", envelope.SessionType,
+				envelope.Type)
+		}
+	}
+
+	return req, envelope, nil
+}
+
+// Run the setter, converting the panic raised by setters which reject the implementation into an error.
+func attach(setter func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	setter()
+	return nil
+}
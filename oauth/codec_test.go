@@ -0,0 +1,112 @@
+package oauth
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/imulab-z/platform-sdk/test"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+func TestRequestCodec(t *testing.T) {
+	s := new(RequestCodecTestSuite)
+	suite.Run(t, s)
+}
+
+type RequestCodecTestSuite struct {
+	suite.Suite
+	client *test.MockClient
+	codec  *RequestCodec
+}
+
+func (s *RequestCodecTestSuite) SetupTest() {
+	s.client = new(test.MockClient)
+	lookup := new(test.MockClientLookup)
+	lookup.On("FindById", s.client.GetId()).Return(s.client, nil)
+	s.codec = NewRequestCodec(lookup)
+}
+
+func (s *RequestCodecTestSuite) TestAuthorizeRequest() {
+	req := NewAuthorizeRequest()
+	req.SetClient(s.client)
+	req.SetRedirectUri(s.client.GetRedirectUris()[0])
+	req.AddResponseTypes(spi.ResponseTypeCode)
+	req.AddScopes("foo", "bar")
+	req.SetState("12345")
+	req.SetCodeChallenge("E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM")
+	req.GetSession().SetSubject("test user")
+	req.GetSession().AddGrantedScopes("foo")
+
+	decoded := s.roundTrip(req)
+	authReq, ok := decoded.(AuthorizeRequest)
+	s.Require().True(ok)
+
+	s.Assert().Equal(req.GetId(), authReq.GetId())
+	s.Assert().Equal(s.client.GetId(), authReq.GetClient().GetId())
+	s.Assert().Equal(req.GetRedirectUri(), authReq.GetRedirectUri())
+	s.Assert().Equal([]string{spi.ResponseTypeCode}, authReq.GetResponseTypes())
+	s.Assert().Equal([]string{"foo", "bar"}, authReq.GetScopes())
+	s.Assert().Equal("12345", authReq.GetState())
+	s.Assert().Equal(req.GetCodeChallenge(), authReq.GetCodeChallenge())
+	s.Assert().Equal("test user", authReq.GetSession().GetSubject())
+	s.Assert().Equal([]string{"foo"}, authReq.GetSession().GetGrantedScopes())
+}
+
+func (s *RequestCodecTestSuite) TestTokenRequest() {
+	req := NewTokenRequest()
+	req.SetClient(s.client)
+	req.AddGrantTypes(spi.GrantTypePassword)
+	req.SetUsername("john")
+	req.SetPassword("s3cret")
+	req.GetSession().SetLastRequestId("previous")
+
+	decoded := s.roundTrip(req)
+	tokenReq, ok := decoded.(TokenRequest)
+	s.Require().True(ok)
+
+	s.Assert().Equal([]string{spi.GrantTypePassword}, tokenReq.GetGrantTypes())
+	s.Assert().Equal("john", tokenReq.GetUsername())
+	s.Assert().Empty(tokenReq.GetPassword())
+	s.Assert().Equal("previous", tokenReq.GetSession().GetLastRequestId())
+}
+
+func (s *RequestCodecTestSuite) TestClone() {
+	req := NewTokenRequest()
+	req.SetClient(s.client)
+	req.AddGrantTypes(spi.GrantTypeCode)
+	req.GetSession().SetSubject("test user")
+	req.GetSession().GetAccessClaims()["foo"] = "bar"
+
+	// the client is attached as is, hence no lookup is needed
+	clone, err := NewRequestCodec(nil).Clone(req)
+	s.Require().Nil(err)
+	s.Assert().Equal(req.GetId(), clone.GetId())
+	s.Assert().True(s.client == clone.GetClient())
+
+	clone.GetSession().SetSubject("someone else")
+	clone.GetSession().GetAccessClaims()["foo"] = "baz"
+	s.Assert().Equal("test user", req.GetSession().GetSubject())
+	s.Assert().Equal("bar", req.GetSession().GetAccessClaims()["foo"])
+}
+
+func (s *RequestCodecTestSuite) TestUnregisteredType() {
+	_, err := s.codec.Encode(&codecTestRequest{Request: NewRequest()})
+	s.Assert().NotNil(err)
+
+	_, err = s.codec.Decode(context.Background(), []byte(`{"type":"unknown","request":{}}`))
+	s.Assert().NotNil(err)
+}
+
+func (s *RequestCodecTestSuite) roundTrip(req Request) Request {
+	data, err := s.codec.Encode(req)
+	s.Require().Nil(err)
+
+	decoded, err := s.codec.Decode(context.Background(), data)
+	s.Require().Nil(err)
+	return decoded
+}
+
+// support: unregistered Request implementation
+type codecTestRequest struct {
+	Request
+}
@@ -0,0 +1,97 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"github.com/imulab-z/platform-sdk/spi"
+	"net/http"
+)
+
+// Entry point of the authorization endpoint. It coordinates the RequestParser, the Validator and the chain of
+// AuthorizeHandler so that an incoming HTTP request is turned into a Response that is ready to be rendered.
+//
+// End user authentication and consent is out of the scope of this SDK. Users may call NewAuthorizeRequest and
+// Authorize separately to interact with the end user in between, or call HandleAuthorizeRequest with an already
+// established session to do everything in one pass.
+//
+// When Transactions is set, the handlers run within a single transaction, which is committed before the response is
+// returned and rolled back upon any error.
+type AuthorizeEndpoint struct {
+	// Factory function to create an empty AuthorizeRequest. When left nil, defaults to NewAuthorizeRequest. Open ID
+	// Connect users should supply a factory creating oidc.AuthorizeRequest.
+	RequestFactory func() AuthorizeRequest
+	Parser         RequestParser
+	Validator      Validator
+	Handlers       []AuthorizeHandler
+	Transactions   spi.TransactionManager
+}
+
+// Parse and validate the authorize request. The returned request is never nil, even when error is not nil, so that the
+// caller can still render the error against the redirect_uri and state that was parsed, if any.
+func (e *AuthorizeEndpoint) NewAuthorizeRequest(ctx context.Context, r *http.Request) (AuthorizeRequest, error) {
+	req := e.newRequest()
+
+	if err := e.Parser.ParseAuthorizeRequest(ctx, r, req); err != nil {
+		return req, err
+	}
+
+	if err := e.Validator.Validate(ctx, req); err != nil {
+		return req, err
+	}
+
+	return req, nil
+}
+
+// Run the request through all handlers and returns the aggregated response. Returns an unsupported_response_type error
+// if any of the requested response types were left unhandled by the handlers.
+func (e *AuthorizeEndpoint) Authorize(ctx context.Context, req AuthorizeRequest) (Response, error) {
+	resp := NewResponse()
+
+	if err := runInTransaction(ctx, e.Transactions, func(ctx context.Context) error {
+		for _, handler := range e.Handlers {
+			if err := handler.Authorize(ctx, req, resp); err != nil {
+				return err
+			}
+		}
+
+		for _, responseType := range req.GetResponseTypes() {
+			if !req.IsResponseTypeHandled(responseType) {
+				return spi.ErrUnsupportedResponseType(fmt.Sprintf("response_type %s was not handled.", responseType))
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Parse, validate and authorize the request in one pass. The given session, which is expected to carry the outcome of
+// end user authentication and consent, is merged into the request session before handlers are invoked. As with
+// NewAuthorizeRequest, the returned request is never nil.
+func (e *AuthorizeEndpoint) HandleAuthorizeRequest(ctx context.Context, r *http.Request, session Session) (AuthorizeRequest, Response, error) {
+	req, err := e.NewAuthorizeRequest(ctx, r)
+	if err != nil {
+		return req, nil, err
+	}
+
+	if session != nil {
+		req.GetSession().Merge(session)
+	}
+
+	resp, err := e.Authorize(ctx, req)
+	if err != nil {
+		return req, nil, err
+	}
+
+	return req, resp, nil
+}
+
+func (e *AuthorizeEndpoint) newRequest() AuthorizeRequest {
+	if e.RequestFactory != nil {
+		return e.RequestFactory()
+	}
+	return NewAuthorizeRequest()
+}
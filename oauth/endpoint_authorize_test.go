@@ -0,0 +1,101 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/imulab-z/platform-sdk/test"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthorizeEndpoint(t *testing.T) {
+	s := new(AuthorizeEndpointTestSuite)
+	suite.Run(t, s)
+}
+
+type AuthorizeEndpointTestSuite struct {
+	suite.Suite
+	endpoint *AuthorizeEndpoint
+	clientId string
+}
+
+func (s *AuthorizeEndpointTestSuite) SetupTest() {
+	client := new(test.MockClient)
+	s.clientId = client.GetId()
+
+	lookup := new(test.MockClientLookup)
+	lookup.On("FindById", client.GetId()).Return(client, nil)
+
+	s.endpoint = &AuthorizeEndpoint{
+		Parser: NewHttpRequestParser(lookup, nil),
+		Validator: &AuthorizeRequestValidator{
+			RequestValidator: &RequestValidator{},
+		},
+		Handlers: []AuthorizeHandler{
+			&AuthorizeCodeHandler{
+				CodeRepo:        &noOpAuthorizeCodeRepository{},
+				CodeStrategy:    NewHmacShaAuthorizeCodeStrategy(32, MustHmacSha256Strategy()),
+				ScopeComparator: EqualityComparator,
+			},
+		},
+	}
+}
+
+func (s *AuthorizeEndpointTestSuite) TestHandleAuthorizeRequest() {
+	session := NewSession()
+	session.SetSubject("test user")
+	session.AddGrantedScopes("foo")
+
+	req, resp, err := s.endpoint.HandleAuthorizeRequest(context.Background(), s.newHttpRequest(spi.ResponseTypeCode), session)
+	s.Assert().Nil(err)
+	s.Assert().Equal("test user", req.GetSession().GetSubject())
+	s.Assert().Equal("12345678", req.GetState())
+	s.Assert().NotEmpty(resp.GetString(Code))
+}
+
+func (s *AuthorizeEndpointTestSuite) TestHandleAuthorizeRequestWithUnhandledResponseType() {
+	kid := "8f1d8a8e-9a3c-4f0b-a7a4-0bbf2bd5b2f4"
+	s.endpoint.Handlers = append(s.endpoint.Handlers, &ImplicitHandler{
+		AccessTokenHelper: &AccessTokenHelper{
+			Lifespan: 30 * time.Minute,
+			Repo:     &NoOpAccessTokenRepo{},
+			Strategy: NewRs256JwtAccessTokenStrategy("test", 30*time.Minute, MustNewJwksWithRsaKeyForSigning(kid), kid),
+		},
+	})
+
+	session := NewSession()
+	session.AddGrantedScopes("foo")
+
+	// ImplicitHandler only handles response_type=token when used alone, hence 'token' is left unhandled.
+	req, resp, err := s.endpoint.HandleAuthorizeRequest(context.Background(), s.newHttpRequest("code%20token"), session)
+	s.Assert().NotNil(err)
+	s.Assert().Equal("unsupported_response_type", err.(*spi.OAuthError).Err)
+	s.Assert().Nil(resp)
+	s.Assert().NotNil(req)
+}
+
+func (s *AuthorizeEndpointTestSuite) TestNewAuthorizeRequestWithInvalidRequest() {
+	req, err := s.endpoint.NewAuthorizeRequest(context.Background(), s.newHttpRequest(spi.ResponseTypeIdToken))
+	s.Assert().NotNil(err)
+	s.Assert().NotNil(req)
+	s.Assert().Equal("12345678", req.GetState())
+}
+
+func (s *AuthorizeEndpointTestSuite) newHttpRequest(responseType string) *http.Request {
+	return httptest.NewRequest(
+		http.MethodGet,
+		fmt.Sprintf(
+			"http://test.org/oauth/authorize?client_id=%s&response_type=%s&redirect_uri=%s&scope=%s&state=%s",
+			s.clientId,
+			responseType,
+			"https://mock.test.org/callback",
+			"foo",
+			"12345678",
+		),
+		nil,
+	)
+}
@@ -0,0 +1,144 @@
+package oauth
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/spi"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// token_type reported for introspected refresh tokens, access tokens are always reported as Bearer.
+	TokenTypeRefreshToken = "refresh_token"
+)
+
+// Token introspection response as defined in RFC 7662. Inactive tokens reveal nothing but the active flag.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientId  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+}
+
+// Token introspection endpoint (RFC 7662). The calling client is authenticated by Authentication. The token is looked
+// up through the repositories and validated with the matching strategy, token_type_hint decides which kind of token
+// is tried first. The expiry of a token is computed from the timestamp of the request it was issued for and the
+// configured lifespan. A zero RefreshTokenLifespan denotes refresh tokens that never expire.
+//
+// When refresh tokens are rotated, RotationRepo and ReuseGracePeriod should be set to those of the RefreshHandler. A
+// rotated refresh token is kept in storage for reuse detection, and is reported inactive once the grace period after
+// its rotation has passed.
+type IntrospectionEndpoint struct {
+	Authentication       ClientAuthentication
+	AccessTokenStrategy  AccessTokenStrategy
+	AccessTokenRepo      AccessTokenRepository
+	AccessTokenLifespan  time.Duration
+	RefreshTokenStrategy RefreshTokenStrategy
+	RefreshTokenRepo     RefreshTokenRepository
+	RefreshTokenLifespan time.Duration
+	RotationRepo         RefreshTokenRotationRepository
+	ReuseGracePeriod     time.Duration
+}
+
+func (e *IntrospectionEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, values, err := ParseAuthenticatedForm(r.Context(), r, e.Authentication)
+	if err != nil {
+		_ = WriteTokenError(w, err)
+		return
+	}
+
+	if len(values.Get(spi.ParamToken)) == 0 {
+		_ = WriteTokenError(w, spi.ErrInvalidRequest("token is required."))
+		return
+	}
+
+	resp := e.Introspect(r.Context(), values.Get(spi.ParamToken), values.Get(spi.ParamTokenTypeHint))
+	_ = writeJson(w, http.StatusOK, resp)
+}
+
+// Introspect the token and returns the introspection response. Any failure to find or validate the token results in
+// an inactive response.
+func (e *IntrospectionEndpoint) Introspect(ctx context.Context, token string, tokenTypeHint string) *IntrospectionResponse {
+	lookups := []func(ctx context.Context, token string) *IntrospectionResponse{
+		e.introspectAccessToken,
+		e.introspectRefreshToken,
+	}
+	if tokenTypeHint == spi.TokenTypeHintRefreshToken {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		if resp := lookup(ctx, token); resp != nil {
+			return resp
+		}
+	}
+
+	return &IntrospectionResponse{Active: false}
+}
+
+func (e *IntrospectionEndpoint) introspectAccessToken(ctx context.Context, token string) *IntrospectionResponse {
+	if e.AccessTokenRepo == nil || e.AccessTokenStrategy == nil {
+		return nil
+	}
+
+	req, err := e.AccessTokenRepo.GetRequest(ctx, token)
+	if err != nil || req == nil {
+		return nil
+	}
+
+	if err := e.AccessTokenStrategy.ValidateToken(ctx, token, req); err != nil {
+		return nil
+	}
+
+	return e.newActiveResponse(req, e.AccessTokenLifespan, "Bearer")
+}
+
+func (e *IntrospectionEndpoint) introspectRefreshToken(ctx context.Context, token string) *IntrospectionResponse {
+	if e.RefreshTokenRepo == nil || e.RefreshTokenStrategy == nil {
+		return nil
+	}
+
+	req, err := e.RefreshTokenRepo.GetRequest(ctx, token)
+	if err != nil || req == nil {
+		return nil
+	}
+
+	if err := e.RefreshTokenStrategy.ValidateToken(ctx, token, req); err != nil {
+		return nil
+	}
+
+	if e.RotationRepo != nil {
+		rotations, err := e.RotationRepo.GetRotations(ctx, req.GetId())
+		if err != nil || rotatedBeyond(rotations, e.ReuseGracePeriod) {
+			return nil
+		}
+	}
+
+	return e.newActiveResponse(req, e.RefreshTokenLifespan, TokenTypeRefreshToken)
+}
+
+// Returns the response describing the token issued for the request, or nil if the token has expired.
+func (e *IntrospectionEndpoint) newActiveResponse(req Request, lifespan time.Duration, tokenType string) *IntrospectionResponse {
+	resp := &IntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(req.GetSession().GetGrantedScopes(), " "),
+		ClientId:  req.GetClient().GetId(),
+		Subject:   req.GetSession().GetSubject(),
+		IssuedAt:  req.GetTimestamp().Unix(),
+		TokenType: tokenType,
+	}
+
+	if lifespan > 0 {
+		expiry := req.GetTimestamp().Add(lifespan)
+		if time.Now().After(expiry) {
+			return nil
+		}
+		resp.ExpiresAt = expiry.Unix()
+	}
+
+	return resp
+}
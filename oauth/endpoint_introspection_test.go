@@ -0,0 +1,216 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/imulab-z/platform-sdk/test"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIntrospectionEndpoint(t *testing.T) {
+	s := new(IntrospectionEndpointTestSuite)
+	suite.Run(t, s)
+}
+
+type IntrospectionEndpointTestSuite struct {
+	suite.Suite
+	client        *test.MockClient
+	accessHelper  *AccessTokenHelper
+	refreshHelper *RefreshTokenHelper
+	endpoint      *IntrospectionEndpoint
+}
+
+func (s *IntrospectionEndpointTestSuite) SetupTest() {
+	kid := "0F6C1F55-54F9-4A5B-9B9C-8B7B0E8A4C31"
+	s.client = new(test.MockClient)
+
+	accessRepo := &inMemTokenRepo{}
+	refreshRepo := &inMemTokenRepo{}
+	accessStrategy := NewRs256JwtAccessTokenStrategy("test", 30*time.Minute, MustNewJwksWithRsaKeyForSigning(kid), kid)
+	refreshStrategy := NewHmacShaRefreshTokenStrategy(32, MustHmacSha256Strategy())
+
+	s.accessHelper = &AccessTokenHelper{Strategy: accessStrategy, Repo: accessRepo, Lifespan: 30 * time.Minute}
+	s.refreshHelper = &RefreshTokenHelper{Strategy: refreshStrategy, Repo: refreshRepo}
+	s.endpoint = &IntrospectionEndpoint{
+		Authentication:       &parserTestClientAuthentication{client: s.client},
+		AccessTokenStrategy:  accessStrategy,
+		AccessTokenRepo:      accessRepo,
+		AccessTokenLifespan:  30 * time.Minute,
+		RefreshTokenStrategy: refreshStrategy,
+		RefreshTokenRepo:     refreshRepo,
+	}
+}
+
+func (s *IntrospectionEndpointTestSuite) TestIntrospectAccessToken() {
+	resp := s.issue()
+
+	body := s.introspect(resp.GetString(AccessToken), "")
+	s.Assert().Equal(true, body["active"])
+	s.Assert().Equal("foo bar", body["scope"])
+	s.Assert().Equal(s.client.GetId(), body["client_id"])
+	s.Assert().Equal("test user", body["sub"])
+	s.Assert().Equal("Bearer", body["token_type"])
+	s.Assert().NotNil(body["exp"])
+	s.Assert().NotNil(body["iat"])
+}
+
+func (s *IntrospectionEndpointTestSuite) TestIntrospectRefreshToken() {
+	resp := s.issue()
+
+	body := s.introspect(resp.GetString(RefreshToken), spi.TokenTypeHintRefreshToken)
+	s.Assert().Equal(true, body["active"])
+	s.Assert().Equal(TokenTypeRefreshToken, body["token_type"])
+	s.Assert().Nil(body["exp"])
+}
+
+func (s *IntrospectionEndpointTestSuite) TestIntrospectRotatedRefreshToken() {
+	rotationRepo := &inMemRotationRepo{}
+	s.endpoint.RotationRepo = rotationRepo
+	s.accessHelper.Mode = PersistSync
+	s.refreshHelper.Mode = PersistSync
+	h := &RefreshHandler{
+		AccessTokenHelper:    s.accessHelper,
+		RefreshTokenHelper:   s.refreshHelper,
+		AccessTokenRepo:      s.endpoint.AccessTokenRepo,
+		RefreshTokenRepo:     s.endpoint.RefreshTokenRepo,
+		RefreshTokenStrategy: s.endpoint.RefreshTokenStrategy,
+		RotationRepo:         rotationRepo,
+	}
+
+	req := NewAuthorizeRequest()
+	req.SetClient(&refreshHandlerTestSuiteClient{})
+	req.GetSession().SetSubject("test user")
+	resp := NewResponse()
+	s.Require().Nil(s.refreshHelper.GenToken(context.Background(), req, resp))
+	parent := resp.GetString(RefreshToken)
+
+	refreshReq := NewTokenRequest()
+	refreshReq.SetClient(&refreshHandlerTestSuiteClient{})
+	refreshReq.AddGrantTypes(spi.GrantTypeRefresh)
+	refreshReq.SetRefreshToken(parent)
+	resp = NewResponse()
+	s.Require().Nil(h.UpdateSession(context.Background(), refreshReq))
+	s.Require().Nil(h.IssueToken(context.Background(), refreshReq, resp))
+
+	s.Assert().Equal(map[string]interface{}{"active": false}, s.introspect(parent, spi.TokenTypeHintRefreshToken))
+	s.Assert().Equal(true, s.introspect(resp.GetString(RefreshToken), spi.TokenTypeHintRefreshToken)["active"])
+
+	// the parent may still be refreshed within the grace period, so it is still active
+	s.endpoint.ReuseGracePeriod = time.Minute
+	s.Assert().Equal(true, s.introspect(parent, spi.TokenTypeHintRefreshToken)["active"])
+}
+
+func (s *IntrospectionEndpointTestSuite) TestIntrospectUnknownToken() {
+	body := s.introspect("unknown", "")
+	s.Assert().Equal(map[string]interface{}{"active": false}, body)
+}
+
+func (s *IntrospectionEndpointTestSuite) TestIntrospectExpiredToken() {
+	req := NewTokenRequest()
+	req.SetClient(s.client)
+	req.SetTimestamp(time.Now().Add(-1 * time.Hour).Unix())
+
+	resp := NewResponse()
+	s.Require().Nil(s.refreshHelper.GenToken(context.Background(), req, resp))
+	time.Sleep(50 * time.Millisecond)
+
+	s.endpoint.RefreshTokenLifespan = 30 * time.Minute
+	body := s.introspect(resp.GetString(RefreshToken), spi.TokenTypeHintRefreshToken)
+	s.Assert().Equal(false, body["active"])
+}
+
+func (s *IntrospectionEndpointTestSuite) TestUnauthenticatedCaller() {
+	f := url.Values{}
+	f.Set(spi.ParamClientId, "unknown")
+	f.Set(spi.ParamToken, "some-token")
+	r := httptest.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader(f.Encode()))
+	r.Header.Set("Content-Type", ContentTypeForm)
+
+	w := httptest.NewRecorder()
+	s.endpoint.ServeHTTP(w, r)
+	s.Assert().Equal(http.StatusUnauthorized, w.Code)
+}
+
+func (s *IntrospectionEndpointTestSuite) issue() Response {
+	req := NewTokenRequest()
+	req.SetClient(s.client)
+	req.GetSession().SetSubject("test user")
+	req.GetSession().AddGrantedScopes("foo", "bar")
+
+	resp := NewResponse()
+	s.Require().Nil(s.accessHelper.GenToken(context.Background(), req, resp))
+	s.Require().Nil(s.refreshHelper.GenToken(context.Background(), req, resp))
+	// tokens are saved asynchronously
+	time.Sleep(50 * time.Millisecond)
+	return resp
+}
+
+func (s *IntrospectionEndpointTestSuite) introspect(token string, hint string) map[string]interface{} {
+	f := url.Values{}
+	f.Set(spi.ParamClientId, s.client.GetId())
+	f.Set(spi.ParamToken, token)
+	if len(hint) > 0 {
+		f.Set(spi.ParamTokenTypeHint, hint)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader(f.Encode()))
+	r.Header.Set("Content-Type", ContentTypeForm)
+
+	w := httptest.NewRecorder()
+	s.endpoint.ServeHTTP(w, r)
+	s.Require().Equal(http.StatusOK, w.Code)
+
+	body := make(map[string]interface{})
+	s.Require().Nil(json.NewDecoder(w.Body).Decode(&body))
+	return body
+}
+
+// support: AccessTokenRepository and RefreshTokenRepository backed by a map
+type inMemTokenRepo struct {
+	sync.Mutex
+	store map[string]Request
+}
+
+func (r *inMemTokenRepo) Save(ctx context.Context, token string, req Request) error {
+	r.Lock()
+	defer r.Unlock()
+	if r.store == nil {
+		r.store = make(map[string]Request)
+	}
+	r.store[token] = req
+	return nil
+}
+
+func (r *inMemTokenRepo) GetRequest(ctx context.Context, token string) (Request, error) {
+	r.Lock()
+	defer r.Unlock()
+	if req, ok := r.store[token]; ok {
+		return req, nil
+	}
+	return nil, spi.ErrInvalidGrant("token not found")
+}
+
+func (r *inMemTokenRepo) Delete(ctx context.Context, token string) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.store, token)
+	return nil
+}
+
+func (r *inMemTokenRepo) DeleteByRequestId(ctx context.Context, requestId string) error {
+	r.Lock()
+	defer r.Unlock()
+	for k, v := range r.store {
+		if v.GetId() == requestId {
+			delete(r.store, k)
+		}
+	}
+	return nil
+}
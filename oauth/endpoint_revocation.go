@@ -0,0 +1,111 @@
+package oauth
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/spi"
+	"net/http"
+)
+
+// Token revocation endpoint (RFC 7009). The calling client is authenticated by Authentication and may only revoke
+// tokens issued to itself. Revoking a refresh token also revokes the access tokens issued from the same request.
+// Unknown or already revoked tokens are silently accepted, as the purpose of the request has been fulfilled. Storage
+// failures, however, are reported as server_error, since the token may still be active.
+type RevocationEndpoint struct {
+	Authentication   ClientAuthentication
+	AccessTokenRepo  AccessTokenRepository
+	RefreshTokenRepo RefreshTokenRepository
+}
+
+func (e *RevocationEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	client, values, err := ParseAuthenticatedForm(r.Context(), r, e.Authentication)
+	if err != nil {
+		_ = WriteTokenError(w, err)
+		return
+	}
+
+	if len(values.Get(spi.ParamToken)) == 0 {
+		_ = WriteTokenError(w, spi.ErrInvalidRequest("token is required."))
+		return
+	}
+
+	if err := e.Revoke(r.Context(), client, values.Get(spi.ParamToken), values.Get(spi.ParamTokenTypeHint)); err != nil {
+		_ = WriteTokenError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusOK)
+}
+
+// Revoke the token on behalf of the client. token_type_hint decides which kind of token is looked up first. Returns
+// nil if the token was revoked or is unknown; returns an error if the token was not issued to the client or the
+// repositories failed to look it up or delete it.
+func (e *RevocationEndpoint) Revoke(ctx context.Context, client spi.OAuthClient, token string, tokenTypeHint string) error {
+	revocations := []func(ctx context.Context, client spi.OAuthClient, token string) (bool, error){
+		e.revokeAccessToken,
+		e.revokeRefreshToken,
+	}
+	if tokenTypeHint == spi.TokenTypeHintRefreshToken {
+		revocations[0], revocations[1] = revocations[1], revocations[0]
+	}
+
+	for _, revoke := range revocations {
+		if found, err := revoke(ctx, client, token); err != nil {
+			return err
+		} else if found {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (e *RevocationEndpoint) revokeAccessToken(ctx context.Context, client spi.OAuthClient, token string) (bool, error) {
+	req, err := e.AccessTokenRepo.GetRequest(ctx, token)
+	if err != nil && IsStorageFailure(err) {
+		return false, spi.AsOAuthError(err)
+	} else if err != nil || req == nil {
+		return false, nil
+	}
+
+	if err := checkTokenOwnership(req, client); err != nil {
+		return true, err
+	}
+
+	if err := e.AccessTokenRepo.Delete(ctx, token); err != nil {
+		return true, spi.AsOAuthError(err)
+	}
+
+	return true, nil
+}
+
+func (e *RevocationEndpoint) revokeRefreshToken(ctx context.Context, client spi.OAuthClient, token string) (bool, error) {
+	req, err := e.RefreshTokenRepo.GetRequest(ctx, token)
+	if err != nil && IsStorageFailure(err) {
+		return false, spi.AsOAuthError(err)
+	} else if err != nil || req == nil {
+		return false, nil
+	}
+
+	if err := checkTokenOwnership(req, client); err != nil {
+		return true, err
+	}
+
+	if err := e.RefreshTokenRepo.Delete(ctx, token); err != nil {
+		return true, spi.AsOAuthError(err)
+	}
+
+	if err := e.AccessTokenRepo.DeleteByRequestId(ctx, req.GetId()); err != nil {
+		return true, spi.AsOAuthError(err)
+	}
+
+	return true, nil
+}
+
+func checkTokenOwnership(req Request, client spi.OAuthClient) error {
+	if req.GetClient().GetId() != client.GetId() {
+		return spi.ErrUnauthorizedClient("token was not issued to this client.")
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/imulab-z/platform-sdk/test"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRevocationEndpoint(t *testing.T) {
+	s := new(RevocationEndpointTestSuite)
+	suite.Run(t, s)
+}
+
+type RevocationEndpointTestSuite struct {
+	suite.Suite
+	client      *test.MockClient
+	accessRepo  *inMemTokenRepo
+	refreshRepo *inMemTokenRepo
+	endpoint    *RevocationEndpoint
+}
+
+func (s *RevocationEndpointTestSuite) SetupTest() {
+	s.client = new(test.MockClient)
+	s.accessRepo = &inMemTokenRepo{}
+	s.refreshRepo = &inMemTokenRepo{}
+	s.endpoint = &RevocationEndpoint{
+		Authentication:   &parserTestClientAuthentication{client: s.client},
+		AccessTokenRepo:  s.accessRepo,
+		RefreshTokenRepo: s.refreshRepo,
+	}
+}
+
+func (s *RevocationEndpointTestSuite) TestRevokeAccessToken() {
+	req := s.save(s.client, "access-token", "refresh-token")
+
+	s.Assert().Equal(http.StatusOK, s.revoke("access-token", spi.TokenTypeHintAccessToken))
+
+	_, err := s.accessRepo.GetRequest(context.Background(), "access-token")
+	s.Assert().NotNil(err)
+	found, err := s.refreshRepo.GetRequest(context.Background(), "refresh-token")
+	s.Assert().Nil(err)
+	s.Assert().Equal(req.GetId(), found.GetId())
+}
+
+func (s *RevocationEndpointTestSuite) TestRevokeRefreshTokenRevokesAccessTokens() {
+	s.save(s.client, "access-token", "refresh-token")
+
+	// wrong hint shall not prevent revocation
+	s.Assert().Equal(http.StatusOK, s.revoke("refresh-token", spi.TokenTypeHintAccessToken))
+
+	_, err := s.refreshRepo.GetRequest(context.Background(), "refresh-token")
+	s.Assert().NotNil(err)
+	_, err = s.accessRepo.GetRequest(context.Background(), "access-token")
+	s.Assert().NotNil(err)
+}
+
+func (s *RevocationEndpointTestSuite) TestRevokeUnknownToken() {
+	s.Assert().Equal(http.StatusOK, s.revoke("unknown", ""))
+}
+
+func (s *RevocationEndpointTestSuite) TestRepositoryFailure() {
+	s.endpoint.AccessTokenRepo = &failingTokenRepo{}
+	s.Assert().Equal(http.StatusInternalServerError, s.revoke("access-token", ""))
+}
+
+func (s *RevocationEndpointTestSuite) TestRevokeTokenOfAnotherClient() {
+	s.save(new(test.MockClient), "access-token", "refresh-token")
+
+	s.Assert().Equal(http.StatusBadRequest, s.revoke("refresh-token", spi.TokenTypeHintRefreshToken))
+
+	_, err := s.refreshRepo.GetRequest(context.Background(), "refresh-token")
+	s.Assert().Nil(err)
+}
+
+func (s *RevocationEndpointTestSuite) save(client spi.OAuthClient, accessToken string, refreshToken string) Request {
+	req := NewTokenRequest()
+	req.SetClient(client)
+	s.Require().Nil(s.accessRepo.Save(context.Background(), accessToken, req))
+	s.Require().Nil(s.refreshRepo.Save(context.Background(), refreshToken, req))
+	return req
+}
+
+func (s *RevocationEndpointTestSuite) revoke(token string, hint string) int {
+	f := url.Values{}
+	f.Set(spi.ParamClientId, s.client.GetId())
+	f.Set(spi.ParamToken, token)
+	if len(hint) > 0 {
+		f.Set(spi.ParamTokenTypeHint, hint)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/oauth/revoke", strings.NewReader(f.Encode()))
+	r.Header.Set("Content-Type", ContentTypeForm)
+
+	w := httptest.NewRecorder()
+	s.endpoint.ServeHTTP(w, r)
+	return w.Code
+}
+
+// support: AccessTokenRepository whose backend is down
+type failingTokenRepo struct {
+	*NoOpAccessTokenRepo
+}
+
+func (r *failingTokenRepo) GetRequest(ctx context.Context, token string) (Request, error) {
+	return nil, errors.New("connection refused")
+}
@@ -0,0 +1,99 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"github.com/imulab-z/platform-sdk/spi"
+	"net/http"
+)
+
+// Entry point of the token endpoint. It coordinates the RequestParser, the Validator and the chain of TokenHandler so
+// that an incoming HTTP request is turned into a Response that is ready to be rendered.
+//
+// Client authentication is performed by the ClientAuthentication the Parser was constructed with. For Open ID Connect
+// deployments, this should be the oidc.AuthenticationHandler, so that the client's registered token_endpoint_auth_method
+// is respected.
+//
+// TokenHandler implementations expect a two phase contract: every handler gets a chance to update the request session
+// before any of them starts issuing tokens. This is because handlers, like oidc.AuthorizeCodeHandler, depend on session
+// knowledge revived by preceding handlers, like oauth.AuthorizeCodeHandler.
+//
+// When Transactions is set, both phases run within a single transaction, which is committed before the response is
+// returned and rolled back upon any error. Token helpers should be configured with PersistSync in this case, so that
+// the tokens are saved within the transaction. Actions which must outlive a refused request, like revoking tokens upon
+// detection of a replay, are performed after the rollback.
+type TokenEndpoint struct {
+	// Factory function to create an empty TokenRequest. When left nil, defaults to NewTokenRequest. Open ID Connect
+	// users should supply a factory creating oidc.TokenRequest.
+	RequestFactory func() TokenRequest
+	Parser         RequestParser
+	Validator      Validator
+	Handlers       []TokenHandler
+	Transactions   spi.TransactionManager
+}
+
+// Parse, validate and process the token request. The returned request is never nil, even when error is not nil.
+func (e *TokenEndpoint) HandleTokenRequest(ctx context.Context, r *http.Request) (TokenRequest, Response, error) {
+	req := e.newRequest()
+
+	if err := e.Parser.ParseTokenRequest(ctx, r, req); err != nil {
+		return req, nil, err
+	}
+
+	if err := e.Validator.Validate(ctx, req); err != nil {
+		return req, nil, err
+	}
+
+	resp, err := e.IssueToken(ctx, req)
+	if err != nil {
+		return req, nil, err
+	}
+
+	return req, resp, nil
+}
+
+// Run the parsed and validated request through the UpdateSession phase of all handlers, and then the IssueToken phase
+// of all handlers. Returns an unsupported_grant_type error if none of the handlers supports the request.
+func (e *TokenEndpoint) IssueToken(ctx context.Context, req TokenRequest) (Response, error) {
+	if !e.isSupported(req) {
+		return nil, spi.ErrUnsupportedGrantType(fmt.Sprintf("grant_type %v is not supported.", req.GetGrantTypes()))
+	}
+
+	resp := NewResponse()
+	if err := runInTransaction(ctx, e.Transactions, func(ctx context.Context) error {
+		for _, handler := range e.Handlers {
+			if err := handler.UpdateSession(ctx, req); err != nil {
+				return err
+			}
+		}
+
+		for _, handler := range e.Handlers {
+			if err := handler.IssueToken(ctx, req, resp); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Returns true if at least one of the handlers accepts the request.
+func (e *TokenEndpoint) isSupported(req TokenRequest) bool {
+	for _, handler := range e.Handlers {
+		if handler.SupportsTokenRequest(req) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *TokenEndpoint) newRequest() TokenRequest {
+	if e.RequestFactory != nil {
+		return e.RequestFactory()
+	}
+	return NewTokenRequest()
+}
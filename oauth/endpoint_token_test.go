@@ -0,0 +1,102 @@
+package oauth
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/imulab-z/platform-sdk/test"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTokenEndpoint(t *testing.T) {
+	s := new(TokenEndpointTestSuite)
+	suite.Run(t, s)
+}
+
+type TokenEndpointTestSuite struct {
+	suite.Suite
+	endpoint *TokenEndpoint
+	trace    *[]string
+	clientId string
+}
+
+func (s *TokenEndpointTestSuite) SetupTest() {
+	client := new(test.MockClient)
+	s.clientId = client.GetId()
+
+	lookup := new(test.MockClientLookup)
+	lookup.On("FindById", client.GetId()).Return(client, nil)
+
+	s.trace = &[]string{}
+	s.endpoint = &TokenEndpoint{
+		Parser: NewHttpRequestParser(lookup, &parserTestClientAuthentication{client: client}),
+		Validator: &TokenRequestValidator{
+			RequestValidator: &RequestValidator{},
+		},
+		Handlers: []TokenHandler{
+			&tokenEndpointTestHandler{name: "first", trace: s.trace},
+			&tokenEndpointTestHandler{name: "second", trace: s.trace},
+		},
+	}
+}
+
+func (s *TokenEndpointTestSuite) TestHandleTokenRequest() {
+	req, resp, err := s.endpoint.HandleTokenRequest(context.Background(), s.newHttpRequest())
+	s.Assert().Nil(err)
+	s.Assert().Equal(s.clientId, req.GetClient().GetId())
+	s.Assert().Equal("second", resp.GetString(AccessToken))
+	s.Assert().Equal([]string{
+		"first.UpdateSession",
+		"second.UpdateSession",
+		"first.IssueToken",
+		"second.IssueToken",
+	}, *s.trace)
+}
+
+func (s *TokenEndpointTestSuite) TestHandleTokenRequestWithUnsupportedGrantType() {
+	s.endpoint.Handlers = []TokenHandler{
+		&ClientCredentialsHandler{},
+	}
+
+	req, resp, err := s.endpoint.HandleTokenRequest(context.Background(), s.newHttpRequest())
+	s.Assert().NotNil(err)
+	s.Assert().Equal("unsupported_grant_type", err.(*spi.OAuthError).Err)
+	s.Assert().NotNil(req)
+	s.Assert().Nil(resp)
+}
+
+func (s *TokenEndpointTestSuite) newHttpRequest() *http.Request {
+	f := url.Values{}
+	f.Set(spi.ParamClientId, s.clientId)
+	f.Set(spi.ParamGrantType, spi.GrantTypeCode)
+	f.Set(spi.ParamCode, "some-code")
+	f.Set(spi.ParamRedirectUri, "https://mock.test.org/callback")
+	r := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(f.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+// support: TokenHandler that records the order of invocation.
+type tokenEndpointTestHandler struct {
+	name  string
+	trace *[]string
+}
+
+func (h *tokenEndpointTestHandler) UpdateSession(ctx context.Context, req TokenRequest) error {
+	*h.trace = append(*h.trace, h.name+".UpdateSession")
+	return nil
+}
+
+func (h *tokenEndpointTestHandler) IssueToken(ctx context.Context, req TokenRequest, resp Response) error {
+	*h.trace = append(*h.trace, h.name+".IssueToken")
+	resp.Set(AccessToken, h.name)
+	return nil
+}
+
+func (h *tokenEndpointTestHandler) SupportsTokenRequest(req TokenRequest) bool {
+	return true
+}
@@ -16,4 +16,16 @@ type TokenHandler interface {
 	IssueToken(ctx context.Context, req TokenRequest, resp Response) error
 	// Internal convenience method to determine whether this handler should be skipped.
 	SupportsTokenRequest(req TokenRequest) bool
-}
\ No newline at end of file
+}
+
+// Optional interface for AuthorizeHandler to advertise the response_type values it handles. Each value is a space
+// delimited combination of response types (i.e. "code id_token"). Used to build discovery documents.
+type ResponseTypesAdvertiser interface {
+	SupportedResponseTypes() []string
+}
+
+// Optional interface for AuthorizeHandler and TokenHandler to advertise the grant types it handles. Used to build
+// discovery documents.
+type GrantTypesAdvertiser interface {
+	SupportedGrantTypes() []string
+}
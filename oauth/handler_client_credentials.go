@@ -3,7 +3,6 @@ package oauth
 import (
 	"context"
 	"github.com/imulab-z/platform-sdk/spi"
-	"sync"
 )
 
 type ClientCredentialsHandler struct {
@@ -51,33 +50,24 @@ func (h *ClientCredentialsHandler) IssueToken(ctx context.Context, req TokenRequ
 		return nil
 	}
 
-	errChan := make(chan error, 1)
-	defer close(errChan)
-
-	wg := new(sync.WaitGroup)
-	wg.Add(2)
-	doAsync(ctx, wg, errChan, func() error {
-		return h.AccessTokenHelper.GenToken(ctx, req, resp)
-	})
-	doAsync(ctx, wg, errChan, func() error {
-		if V(req.GetSession().GetGrantedScopes()).Contains(spi.ScopeOfflineAccess) {
-			return h.RefreshTokenHelper.GenToken(ctx, req, resp)
-		} else {
-			return nil
-		}
-	})
-	wg.Wait()
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case err := <-errChan:
-		return err
-	default:
-		return nil
-	}
+	return doAll(
+		func() error {
+			return h.AccessTokenHelper.GenToken(ctx, req, resp)
+		},
+		func() error {
+			if V(req.GetSession().GetGrantedScopes()).Contains(spi.ScopeOfflineAccess) {
+				return h.RefreshTokenHelper.GenToken(ctx, req, resp)
+			} else {
+				return nil
+			}
+		},
+	)
 }
 
 func (h *ClientCredentialsHandler) SupportsTokenRequest(req TokenRequest) bool {
 	return V(req.GetGrantTypes()).ContainsExactly(spi.GrantTypeClient)
 }
+
+func (h *ClientCredentialsHandler) SupportedGrantTypes() []string {
+	return []string{spi.GrantTypeClient}
+}
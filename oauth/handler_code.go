@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/sirupsen/logrus"
 	"github.com/thoas/go-funk"
 )
 
@@ -13,6 +14,11 @@ type AuthorizeCodeHandler struct {
 	CodeRepo           AuthorizeCodeRepository
 	AccessTokenHelper  *AccessTokenHelper
 	RefreshTokenHelper *RefreshTokenHelper
+	// When true, public clients must supply a PKCE code_challenge to obtain an authorization code.
+	RequirePkceForPublicClients bool
+	// Rotations recorded by the RefreshHandler. When set, the replay of an authorization code also revokes the tokens
+	// derived through refresh from the ones it issued; otherwise, only the latter are revoked.
+	RotationRepo RefreshTokenRotationRepository
 }
 
 func (h *AuthorizeCodeHandler) Authorize(ctx context.Context, req AuthorizeRequest, resp Response) error {
@@ -54,6 +60,10 @@ func (h *AuthorizeCodeHandler) checkAuthorizePrerequisite(req AuthorizeRequest)
 		return ErrClientRejectScope
 	}
 
+	if h.requiresPkce(req.GetClient()) && len(req.GetCodeChallenge()) == 0 {
+		return spi.ErrInvalidRequest("code_challenge is required for public clients")
+	}
+
 	return nil
 }
 
@@ -72,18 +82,29 @@ func (h *AuthorizeCodeHandler) SupportsAuthorizeRequest(req AuthorizeRequest) bo
 	return V(req.GetResponseTypes()).Contains(spi.ResponseTypeCode)
 }
 
+func (h *AuthorizeCodeHandler) SupportedResponseTypes() []string {
+	return []string{spi.ResponseTypeCode}
+}
+
 func (h *AuthorizeCodeHandler) UpdateSession(ctx context.Context, req TokenRequest) error {
 	if !h.SupportsTokenRequest(req) {
 		return nil
 	}
 
-	// for security purposes, the requested code shall be deleted after a single use, either by
-	// the authorized client or by a malicious party.
-	defer func() {
-		go h.CodeRepo.Delete(context.Background(), req.GetCode())
-	}()
+	// codes are marked as consumed by repositories that are capable of replay detection
+	if consumable, ok := h.CodeRepo.(ConsumableAuthorizeCodeRepository); ok {
+		return h.redeemConsumableCode(ctx, consumable, req)
+	}
 
+	// for security purposes, the requested code shall be deleted after a single use, either by
+	// the authorized client or by a malicious party. As a failed request rolls back its transaction,
+	// the code is deleted after the rollback in that case.
 	if oldReq, err := h.reviveAuthorizeRequest(ctx, req); err != nil {
+		return failAfterRollback(ctx, err, func(ctx context.Context) error {
+			_ = h.deleteCode(ctx, req.GetCode())
+			return nil
+		})
+	} else if err := h.deleteCode(ctx, req.GetCode()); err != nil {
 		return err
 	} else {
 		req.GetSession().SetLastRequestId(oldReq.GetId())
@@ -93,6 +114,83 @@ func (h *AuthorizeCodeHandler) UpdateSession(ctx context.Context, req TokenReque
 	return nil
 }
 
+// Marks the code as consumed before the request is revived, so that any use, either by the authorized client or by a
+// malicious party, counts. If the code had already been consumed, tokens issued to the consuming request are revoked and
+// an invalid_grant error is returned. The marking takes part in the transaction of the request, so that concurrent
+// redemptions are serialized. Upon failure, the revocation, or the marking of a code which failed to be redeemed, is
+// performed after the transaction is rolled back.
+func (h *AuthorizeCodeHandler) redeemConsumableCode(ctx context.Context, repo ConsumableAuthorizeCodeRepository,
+	req TokenRequest) error {
+	previous, err := repo.MarkConsumed(ctx, req.GetCode(), req.GetId())
+	if err != nil {
+		return spi.AsOAuthError(err)
+	}
+
+	if len(previous) > 0 {
+		return failAfterRollback(ctx, spi.ErrInvalidGrant("authorization code has already been used."),
+			func(ctx context.Context) error {
+				if err := h.revokeTokens(ctx, previous); err != nil {
+					logrus.WithFields(logrus.Fields{
+						"error":      err,
+						"request_id": previous,
+					}).Errorln("failed to revoke tokens issued by replayed authorization code.")
+					return err
+				}
+				return nil
+			})
+	}
+
+	oldReq, err := h.reviveAuthorizeRequest(ctx, req)
+	if err != nil {
+		return failAfterRollback(ctx, err, func(ctx context.Context) error {
+			_, _ = repo.MarkConsumed(ctx, req.GetCode(), req.GetId())
+			return nil
+		})
+	}
+
+	req.GetSession().SetLastRequestId(oldReq.GetId())
+	req.GetSession().Merge(oldReq.GetSession())
+
+	return nil
+}
+
+// Revoke the access tokens and refresh tokens issued to the request, along with those derived from them through refresh
+// when RotationRepo is set.
+func (h *AuthorizeCodeHandler) revokeTokens(ctx context.Context, requestId string) error {
+	var (
+		accessRepo  AccessTokenRepository
+		refreshRepo RefreshTokenRepository
+	)
+	if h.AccessTokenHelper != nil {
+		accessRepo = h.AccessTokenHelper.Repo
+	}
+	if h.RefreshTokenHelper != nil {
+		refreshRepo = h.RefreshTokenHelper.Repo
+	}
+
+	return revokeFamily(ctx, accessRepo, refreshRepo, h.RotationRepo, requestId)
+}
+
+// Removes the authorization code. The removal is performed in the background, unless the context carries a transaction
+// or tokens are persisted synchronously, in which case removal failures are returned.
+func (h *AuthorizeCodeHandler) deleteCode(ctx context.Context, code string) error {
+	mode := PersistAsync
+	if h.AccessTokenHelper != nil {
+		mode = h.AccessTokenHelper.Mode
+	}
+
+	if !persistSequentially(ctx, mode) {
+		go h.CodeRepo.Delete(context.Background(), code)
+		return nil
+	}
+
+	if err := h.CodeRepo.Delete(ctx, code); err != nil {
+		return spi.AsOAuthError(err)
+	}
+
+	return nil
+}
+
 func (h *AuthorizeCodeHandler) IssueToken(ctx context.Context, req TokenRequest, resp Response) error {
 	if !h.SupportsTokenRequest(req) {
 		return nil
@@ -142,9 +240,39 @@ func (h *AuthorizeCodeHandler) reviveAuthorizeRequest(ctx context.Context, req T
 		return nil, spi.ErrUnauthorizedClient("authorization code was issued to a different redirect uri.")
 	}
 
+	if err := h.checkCodeVerifier(req, oldReq); err != nil {
+		return nil, err
+	}
+
 	return oldReq, nil
 }
 
+// Returns nil if the code_verifier supplied in the token request proves possession of the code_challenge supplied in
+// the authorize request; otherwise returns an invalid_grant error.
+func (h *AuthorizeCodeHandler) checkCodeVerifier(req TokenRequest, oldReq AuthorizeRequest) error {
+	if len(oldReq.GetCodeChallenge()) > 0 {
+		return VerifyCodeVerifier(req.GetCodeVerifier(), oldReq.GetCodeChallenge(), CodeChallengeMethodOf(oldReq))
+	}
+
+	if len(req.GetCodeVerifier()) > 0 {
+		return spi.ErrInvalidGrant("code_verifier supplied but authorization code was issued without code_challenge.")
+	}
+
+	if h.requiresPkce(req.GetClient()) {
+		return spi.ErrInvalidGrant("code_verifier is required for public clients.")
+	}
+
+	return nil
+}
+
+func (h *AuthorizeCodeHandler) requiresPkce(client spi.OAuthClient) bool {
+	return h.RequirePkceForPublicClients && client.GetType() == spi.ClientTypePublic
+}
+
 func (h *AuthorizeCodeHandler) SupportsTokenRequest(req TokenRequest) bool {
 	return V(req.GetGrantTypes()).ContainsExactly(spi.GrantTypeCode)
 }
+
+func (h *AuthorizeCodeHandler) SupportedGrantTypes() []string {
+	return []string{spi.GrantTypeCode}
+}
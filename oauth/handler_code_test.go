@@ -7,6 +7,7 @@ import (
 	"github.com/imulab-z/platform-sdk/test"
 	"github.com/stretchr/testify/suite"
 	"testing"
+	"time"
 )
 
 func TestAuthorizeCodeHandler(t *testing.T) {
@@ -52,6 +53,177 @@ func (s *AuthorizeCodeHandlerTestSuite) TestAuthorize() {
 	s.Assert().NotEmpty(resp.GetString(Code))
 }
 
+func (s *AuthorizeCodeHandlerTestSuite) TestAuthorizePublicClientWithoutPkce() {
+	s.h.RequirePkceForPublicClients = true
+	client := new(pkcePublicClient)
+
+	req := NewAuthorizeRequest()
+	req.AddResponseTypes(spi.ResponseTypeCode)
+	req.SetClient(client)
+	req.SetRedirectUri(client.GetRedirectUris()[0])
+
+	err := s.h.Authorize(context.Background(), req, NewResponse())
+	s.Assert().NotNil(err)
+	s.Assert().Equal("invalid_request", err.(*spi.OAuthError).Err)
+}
+
+func (s *AuthorizeCodeHandlerTestSuite) TestUpdateSessionWithPkce() {
+	s.h.RequirePkceForPublicClients = true
+	client := new(pkcePublicClient)
+
+	for _, v := range []struct {
+		name        string
+		challenge   string
+		verifier    string
+		expectError bool
+	}{
+		{
+			name:        "matching verifier",
+			challenge:   "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+			verifier:    "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk",
+			expectError: false,
+		},
+		{
+			name:        "wrong verifier",
+			challenge:   "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+			verifier:    "wrongwrongwrongwrongwrongwrongwrongwrongwrong",
+			expectError: true,
+		},
+		{
+			name:        "missing challenge for public client",
+			challenge:   "",
+			verifier:    "",
+			expectError: true,
+		},
+	} {
+		authReq := NewAuthorizeRequest()
+		authReq.AddResponseTypes(spi.ResponseTypeCode)
+		authReq.SetClient(client)
+		authReq.SetRedirectUri(client.GetRedirectUris()[0])
+		authReq.SetCodeChallenge(v.challenge)
+		authReq.SetCodeChallengeMethod(spi.CodeChallengeMethodS256)
+
+		code, err := s.h.CodeStrategy.NewCode(context.Background(), authReq)
+		s.Require().Nil(err)
+		s.h.CodeRepo = &singleAuthorizeCodeRepository{req: authReq}
+
+		tokenReq := NewTokenRequest()
+		tokenReq.AddGrantTypes(spi.GrantTypeCode)
+		tokenReq.SetClient(client)
+		tokenReq.SetRedirectUri(client.GetRedirectUris()[0])
+		tokenReq.SetCode(code)
+		tokenReq.SetCodeVerifier(v.verifier)
+
+		err = s.h.UpdateSession(context.Background(), tokenReq)
+		if v.expectError {
+			s.Assert().NotNil(err, v.name)
+		} else {
+			s.Assert().Nil(err, v.name)
+		}
+	}
+}
+
+func (s *AuthorizeCodeHandlerTestSuite) TestReplayRevokesTokens() {
+	kid := "9D3E5A27-61B4-4F0C-8E1A-7C2B9D4F6E30"
+	accessRepo, refreshRepo := &inMemTokenRepo{}, &inMemTokenRepo{}
+	s.h.AccessTokenHelper = &AccessTokenHelper{
+		Repo:     accessRepo,
+		Strategy: NewRs256JwtAccessTokenStrategy("test", 30*time.Minute, MustNewJwksWithRsaKeyForSigning(kid), kid),
+		Lifespan: 30 * time.Minute,
+		Mode:     PersistSync,
+	}
+	s.h.RefreshTokenHelper = &RefreshTokenHelper{
+		Repo:     refreshRepo,
+		Strategy: NewHmacShaRefreshTokenStrategy(32, MustHmacSha256Strategy()),
+		Mode:     PersistSync,
+	}
+
+	client := new(test.MockClient)
+	authReq := NewAuthorizeRequest()
+	authReq.AddResponseTypes(spi.ResponseTypeCode)
+	authReq.SetClient(client)
+	authReq.SetRedirectUri(client.GetRedirectUris()[0])
+	authReq.GetSession().AddGrantedScopes(spi.ScopeOfflineAccess)
+
+	code, err := s.h.CodeStrategy.NewCode(context.Background(), authReq)
+	s.Require().Nil(err)
+	s.h.CodeRepo = &consumableAuthorizeCodeRepository{
+		singleAuthorizeCodeRepository: singleAuthorizeCodeRepository{req: authReq},
+		consumers:                     make(map[string]string),
+	}
+
+	redeem := func() (Response, error) {
+		tokenReq := NewTokenRequest()
+		tokenReq.AddGrantTypes(spi.GrantTypeCode)
+		tokenReq.SetClient(client)
+		tokenReq.SetRedirectUri(client.GetRedirectUris()[0])
+		tokenReq.SetCode(code)
+
+		if err := s.h.UpdateSession(context.Background(), tokenReq); err != nil {
+			return nil, err
+		}
+		resp := NewResponse()
+		if err := s.h.IssueToken(context.Background(), tokenReq, resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	resp, err := redeem()
+	s.Require().Nil(err)
+	s.Require().Len(accessRepo.store, 1)
+	s.Require().Len(refreshRepo.store, 1)
+
+	_, err = redeem()
+	s.Require().NotNil(err)
+	s.Assert().Equal("invalid_grant", err.(*spi.OAuthError).Err)
+
+	_, err = accessRepo.GetRequest(context.Background(), resp.GetString(AccessToken))
+	s.Assert().NotNil(err)
+	_, err = refreshRepo.GetRequest(context.Background(), resp.GetString(RefreshToken))
+	s.Assert().NotNil(err)
+}
+
+// support: public client
+type pkcePublicClient struct {
+	test.MockClient
+}
+
+func (c *pkcePublicClient) GetType() string {
+	return spi.ClientTypePublic
+}
+
+// support: AuthorizeCodeRepository always returning the same request
+type singleAuthorizeCodeRepository struct {
+	req AuthorizeRequest
+}
+
+func (r *singleAuthorizeCodeRepository) GetRequest(ctx context.Context, code string) (AuthorizeRequest, error) {
+	return r.req, nil
+}
+
+func (r *singleAuthorizeCodeRepository) Save(ctx context.Context, code string, req AuthorizeRequest) error {
+	return nil
+}
+
+func (r *singleAuthorizeCodeRepository) Delete(ctx context.Context, code string) error {
+	return nil
+}
+
+// support: ConsumableAuthorizeCodeRepository always returning the same request
+type consumableAuthorizeCodeRepository struct {
+	singleAuthorizeCodeRepository
+	consumers map[string]string
+}
+
+func (r *consumableAuthorizeCodeRepository) MarkConsumed(ctx context.Context, code string, requestId string) (string, error) {
+	if previous, ok := r.consumers[code]; ok {
+		return previous, nil
+	}
+	r.consumers[code] = requestId
+	return "", nil
+}
+
 type noOpAuthorizeCodeRepository struct {}
 
 func (_ *noOpAuthorizeCodeRepository) GetRequest(ctx context.Context, code string) (AuthorizeRequest, error) {
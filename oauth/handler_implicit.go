@@ -31,3 +31,11 @@ func (h *ImplicitHandler) SupportsAuthorizeRequest(req AuthorizeRequest) bool {
 	return V(req.GetResponseTypes()).ContainsExactly(spi.ResponseTypeToken)
 }
 
+func (h *ImplicitHandler) SupportedResponseTypes() []string {
+	return []string{spi.ResponseTypeToken}
+}
+
+func (h *ImplicitHandler) SupportedGrantTypes() []string {
+	return []string{spi.GrantTypeImplicit}
+}
+
@@ -0,0 +1,80 @@
+package oauth
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/spi"
+)
+
+// TokenHandler for the Resource Owner Password Credentials grant. This grant is only meant for legacy first-party
+// clients which have not yet migrated to the authorization code flow.
+type PasswordHandler struct {
+	Authenticator      spi.ResourceOwnerAuthenticator
+	AccessTokenHelper  *AccessTokenHelper
+	RefreshTokenHelper *RefreshTokenHelper
+	ScopeComparator    Comparator
+}
+
+func (h *PasswordHandler) UpdateSession(ctx context.Context, req TokenRequest) error {
+	if !h.SupportsTokenRequest(req) {
+		return nil
+	}
+
+	if err := h.checkClientCapability(req); err != nil {
+		return err
+	}
+
+	subject, err := h.Authenticator.Authenticate(ctx, req.GetUsername(), req.GetPassword())
+	if err != nil {
+		if err == spi.ErrBadCredentials {
+			return spi.ErrInvalidGrant("invalid resource owner credentials.")
+		}
+		return spi.AsOAuthError(err)
+	}
+
+	req.GetSession().SetSubject(subject)
+	// resource owner is deemed to have granted all requested scopes by handing out credentials
+	req.GetSession().AddGrantedScopes(req.GetScopes()...)
+
+	return nil
+}
+
+// Checks the client capability to request token through password flow. Returns nil if the capability checks out;
+// otherwise returns a non-nil error if the client didn't register password grant type or doesn't accept the requested
+// scopes.
+func (h *PasswordHandler) checkClientCapability(req TokenRequest) error {
+	if !ClientRegisteredGrantType(req.GetClient(), spi.GrantTypePassword) {
+		return spi.ErrUnauthorizedClient("client unable to use password grant.")
+	}
+
+	if !ClientAcceptsScopes(req, h.ScopeComparator) {
+		return spi.ErrInvalidScope("scope is not accepted by client.")
+	}
+
+	return nil
+}
+
+func (h *PasswordHandler) IssueToken(ctx context.Context, req TokenRequest, resp Response) error {
+	if !h.SupportsTokenRequest(req) {
+		return nil
+	}
+
+	if err := h.AccessTokenHelper.GenToken(ctx, req, resp); err != nil {
+		return err
+	}
+
+	if V(req.GetSession().GetGrantedScopes()).Contains(spi.ScopeOfflineAccess) {
+		if err := h.RefreshTokenHelper.GenToken(ctx, req, resp); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *PasswordHandler) SupportsTokenRequest(req TokenRequest) bool {
+	return V(req.GetGrantTypes()).ContainsExactly(spi.GrantTypePassword)
+}
+
+func (h *PasswordHandler) SupportedGrantTypes() []string {
+	return []string{spi.GrantTypePassword}
+}
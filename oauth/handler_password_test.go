@@ -0,0 +1,129 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+func TestPasswordHandler(t *testing.T) {
+	s := new(PasswordHandlerTestSuite)
+	suite.Run(t, s)
+}
+
+type PasswordHandlerTestSuite struct {
+	suite.Suite
+	h *PasswordHandler
+}
+
+func (s *PasswordHandlerTestSuite) SetupTest() {
+	kid := "9D0B6A7C-2E3B-4C1F-8E7D-5A4B3C2D1E0F"
+	s.h = &PasswordHandler{
+		Authenticator:   &passwordHandlerTestSuiteAuthenticator{},
+		ScopeComparator: EqualityComparator,
+		AccessTokenHelper: &AccessTokenHelper{
+			Repo: &NoOpAccessTokenRepo{},
+			Strategy: NewRs256JwtAccessTokenStrategy(
+				"test",
+				30*time.Minute,
+				MustNewJwksWithRsaKeyForSigning(kid),
+				kid,
+			),
+			Lifespan: 30 * time.Minute,
+		},
+		RefreshTokenHelper: &RefreshTokenHelper{
+			Repo:     &NoOpRefreshTokenRepo{},
+			Strategy: NewHmacShaRefreshTokenStrategy(32, MustHmacSha256Strategy()),
+		},
+	}
+}
+
+func (s *PasswordHandlerTestSuite) TestIssueToken() {
+	req := NewTokenRequest()
+	req.AddGrantTypes(spi.GrantTypePassword)
+	req.AddScopes("foo", spi.ScopeOfflineAccess)
+	req.SetClient(&passwordHandlerTestSuiteClient{})
+	req.SetUsername("alice")
+	req.SetPassword("s3cret")
+
+	resp := NewResponse()
+
+	err := s.h.UpdateSession(context.Background(), req)
+	s.Assert().Nil(err)
+	s.Assert().Equal("user/alice", req.GetSession().GetSubject())
+	s.Assert().True(V(req.GetSession().GetGrantedScopes()).Contains("foo", spi.ScopeOfflineAccess))
+
+	err = s.h.IssueToken(context.Background(), req, resp)
+	s.Assert().Nil(err)
+	s.Assert().NotEmpty(resp.GetString(AccessToken))
+	s.Assert().NotEmpty(resp.GetString(RefreshToken))
+}
+
+func (s *PasswordHandlerTestSuite) TestBadCredentials() {
+	req := NewTokenRequest()
+	req.AddGrantTypes(spi.GrantTypePassword)
+	req.AddScopes("foo")
+	req.SetClient(&passwordHandlerTestSuiteClient{})
+	req.SetUsername("alice")
+	req.SetPassword("wrong")
+
+	err := s.h.UpdateSession(context.Background(), req)
+	s.Assert().NotNil(err)
+	s.Assert().Equal("invalid_grant", err.(*spi.OAuthError).Err)
+}
+
+func (s *PasswordHandlerTestSuite) TestAuthenticatorFailure() {
+	req := NewTokenRequest()
+	req.AddGrantTypes(spi.GrantTypePassword)
+	req.AddScopes("foo")
+	req.SetClient(&passwordHandlerTestSuiteClient{})
+	req.SetUsername("bob")
+	req.SetPassword("s3cret")
+
+	err := s.h.UpdateSession(context.Background(), req)
+	s.Assert().NotNil(err)
+	s.Assert().Equal("server_error", err.(*spi.OAuthError).Err)
+}
+
+func (s *PasswordHandlerTestSuite) TestUnsupportedRequest() {
+	req := NewTokenRequest()
+	req.AddGrantTypes(spi.GrantTypeClient)
+
+	s.Assert().False(s.h.SupportsTokenRequest(req))
+	s.Assert().Nil(s.h.UpdateSession(context.Background(), req))
+}
+
+type passwordHandlerTestSuiteAuthenticator struct{}
+
+func (a *passwordHandlerTestSuiteAuthenticator) Authenticate(ctx context.Context, username string, password string) (string, error) {
+	if username == "alice" && password == "s3cret" {
+		return "user/alice", nil
+	}
+	if username == "bob" {
+		return "", errors.New("directory unavailable")
+	}
+	return "", spi.ErrBadCredentials
+}
+
+type passwordHandlerTestSuiteClient struct {
+	*panicClient
+}
+
+func (c *passwordHandlerTestSuiteClient) GetId() string {
+	return "client/6f1c7b0e-3a9e-4d0c-9a57-7f5c1f2b8e44"
+}
+
+func (c *passwordHandlerTestSuiteClient) GetType() string {
+	return spi.ClientTypeConfidential
+}
+
+func (c *passwordHandlerTestSuiteClient) GetGrantTypes() []string {
+	return []string{spi.GrantTypePassword}
+}
+
+func (c *passwordHandlerTestSuiteClient) GetScopes() []string {
+	return []string{"foo", "bar", spi.ScopeOfflineAccess}
+}
@@ -3,15 +3,24 @@ package oauth
 import (
 	"context"
 	"github.com/imulab-z/platform-sdk/spi"
-	"sync"
+	"github.com/sirupsen/logrus"
+	"time"
 )
 
+// TokenHandler for the refresh_token grant. Tokens of the refreshed request are replaced by new ones.
+//
+// When RotationRepo is set, refresh tokens are rotated with reuse detection: instead of being deleted, the refreshed
+// token is kept and its rotation is recorded. Presenting an already rotated refresh token is deemed a replay, upon which
+// all tokens issued from it onwards are revoked and the request is refused. A second refresh from the same client within
+// ReuseGracePeriod after the rotation is tolerated, to accommodate concurrent refreshes.
 type RefreshHandler struct {
 	AccessTokenHelper    *AccessTokenHelper
 	RefreshTokenHelper   *RefreshTokenHelper
 	AccessTokenRepo      AccessTokenRepository
 	RefreshTokenRepo     RefreshTokenRepository
 	RefreshTokenStrategy RefreshTokenStrategy
+	RotationRepo         RefreshTokenRotationRepository
+	ReuseGracePeriod     time.Duration
 }
 
 func (h *RefreshHandler) UpdateSession(ctx context.Context, req TokenRequest) error {
@@ -24,6 +33,10 @@ func (h *RefreshHandler) UpdateSession(ctx context.Context, req TokenRequest) er
 		return err
 	}
 
+	if err := h.detectReuse(ctx, oldReq); err != nil {
+		return err
+	}
+
 	req.GetSession().SetLastRequestId(oldReq.GetId())
 	req.GetSession().Merge(oldReq.GetSession())
 
@@ -52,14 +65,131 @@ func (h *RefreshHandler) reviveAuthorizeRequest(ctx context.Context, req TokenRe
 		return nil, err
 	}
 
+	if oldReq.GetClient().GetId() != req.GetClient().GetId() {
+		return nil, spi.ErrInvalidGrant("refresh token was issued to another client.")
+	}
+
 	return oldReq, nil
 }
 
+// Returns an invalid_grant error after revoking the family if the tokens of the request had already been rotated outside
+// the grace period. Returns nil if rotation is not enabled, or the request was never rotated.
+func (h *RefreshHandler) detectReuse(ctx context.Context, oldReq Request) error {
+	if h.RotationRepo == nil {
+		return nil
+	}
+
+	rotations, err := h.RotationRepo.GetRotations(ctx, oldReq.GetId())
+	if err != nil {
+		return spi.AsOAuthError(err)
+	}
+
+	if !h.isReused(rotations) {
+		return nil
+	}
+
+	return h.refuseReuse(ctx, oldReq.GetId())
+}
+
+// Returns true if the rotations indicate a reuse, that is, a rotation happened outside the grace period.
+func (h *RefreshHandler) isReused(rotations []*RefreshTokenRotation) bool {
+	return rotatedBeyond(rotations, h.ReuseGracePeriod)
+}
+
+// Returns true if the earliest of the rotations happened longer than gracePeriod ago. The refreshed token is spent from
+// then on. Returns false if there are no rotations.
+func rotatedBeyond(rotations []*RefreshTokenRotation, gracePeriod time.Duration) bool {
+	if len(rotations) == 0 {
+		return false
+	}
+
+	if gracePeriod > 0 {
+		first := rotations[0].RotatedAt
+		for _, r := range rotations {
+			if r.RotatedAt.Before(first) {
+				first = r.RotatedAt
+			}
+		}
+		if time.Since(first) <= gracePeriod {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns an invalid_grant error, and revokes the family of the request.
+func (h *RefreshHandler) refuseReuse(ctx context.Context, requestId string) error {
+	// the request is about to fail, revocation must survive the rollback of any transaction
+	return failAfterRollback(ctx, spi.ErrInvalidGrant("refresh token has already been used."),
+		func(ctx context.Context) error {
+			if err := h.revokeFamily(ctx, requestId); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"error":      err,
+					"request_id": requestId,
+				}).Errorln("failed to revoke refresh token family.")
+				return err
+			}
+			return nil
+		})
+}
+
+// Revoke all tokens issued to the request and the requests derived from it through refresh.
+func (h *RefreshHandler) revokeFamily(ctx context.Context, requestId string) error {
+	return revokeFamily(ctx, h.AccessTokenRepo, h.RefreshTokenRepo, h.RotationRepo, requestId)
+}
+
+// Revoke all tokens issued to the request and the requests derived from it through refresh, as recorded by the
+// rotation repository. Only the tokens of the request itself are revoked when rotationRepo is nil. Token repositories
+// left nil are skipped.
+func revokeFamily(ctx context.Context, accessRepo AccessTokenRepository, refreshRepo RefreshTokenRepository,
+	rotationRepo RefreshTokenRotationRepository, requestId string) error {
+	queue := []string{requestId}
+	visited := make(map[string]struct{})
+
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[id]; ok {
+			continue
+		}
+		visited[id] = struct{}{}
+
+		if accessRepo != nil {
+			if err := accessRepo.DeleteByRequestId(ctx, id); err != nil {
+				return err
+			}
+		}
+		if refreshRepo != nil {
+			if err := refreshRepo.DeleteByRequestId(ctx, id); err != nil {
+				return err
+			}
+		}
+
+		if rotationRepo == nil {
+			continue
+		}
+		rotations, err := rotationRepo.GetRotations(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, r := range rotations {
+			queue = append(queue, r.ChildRequestId)
+		}
+	}
+
+	return nil
+}
+
 func (h *RefreshHandler) IssueToken(ctx context.Context, req TokenRequest, resp Response) error {
 	if !h.SupportsTokenRequest(req) {
 		return nil
 	}
 
+	if h.RotationRepo != nil {
+		return h.rotateTokens(ctx, req, resp)
+	}
+
 	if err := h.deleteOldTokens(ctx, req); err != nil {
 		return err
 	}
@@ -71,58 +201,59 @@ func (h *RefreshHandler) IssueToken(ctx context.Context, req TokenRequest, resp
 	return nil
 }
 
-// Returns nil if successfully removed both access tokens and refresh tokens associated with the authorization request.
-// Otherwise, returns a non-nil error. The removal of the two token types are executed asynchronously.
-func (h *RefreshHandler) deleteOldTokens(ctx context.Context, req TokenRequest) error {
-	errChan := make(chan error, 1)
-	defer close(errChan)
+// Returns nil if the access tokens of the refreshed request were removed, new tokens were issued and the rotation was
+// recorded. The refreshed refresh token is kept so that its reuse can be detected. Reuse is checked again against the
+// rotations recorded before this one, so that a concurrent refresh which also passed detectReuse is caught.
+func (h *RefreshHandler) rotateTokens(ctx context.Context, req TokenRequest, resp Response) error {
+	if err := h.AccessTokenRepo.DeleteByRequestId(ctx, req.GetSession().GetLastRequestId()); err != nil {
+		return err
+	}
 
-	wg := new(sync.WaitGroup)
-	wg.Add(2)
-	doAsync(ctx, wg, errChan, func() error {
-		return h.AccessTokenRepo.DeleteByRequestId(ctx, req.GetSession().GetLastRequestId())
+	previous, err := h.RotationRepo.SaveRotation(ctx, &RefreshTokenRotation{
+		ParentRequestId: req.GetSession().GetLastRequestId(),
+		ChildRequestId:  req.GetId(),
+		RotatedAt:       time.Now(),
 	})
-	doAsync(ctx, wg, errChan, func() error {
-		return h.RefreshTokenRepo.DeleteByRequestId(ctx, req.GetSession().GetLastRequestId())
-	})
-	wg.Wait()
+	if err != nil {
+		return spi.AsOAuthError(err)
+	}
 
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case err := <-errChan:
-		return err
-	default:
-		return nil
+	if h.isReused(previous) {
+		return h.refuseReuse(ctx, req.GetSession().GetLastRequestId())
 	}
+
+	return h.issueNewTokens(ctx, req, resp)
+}
+
+// Returns nil if successfully removed both access tokens and refresh tokens associated with the authorization request.
+// Otherwise, returns a non-nil error.
+func (h *RefreshHandler) deleteOldTokens(ctx context.Context, req TokenRequest) error {
+	return doAll(
+		func() error {
+			return h.AccessTokenRepo.DeleteByRequestId(ctx, req.GetSession().GetLastRequestId())
+		},
+		func() error {
+			return h.RefreshTokenRepo.DeleteByRequestId(ctx, req.GetSession().GetLastRequestId())
+		},
+	)
 }
 
 // Returns nil if successfully issued both new access token and new refresh token. Otherwise returns a non-nil error.
-// The issuing process of both token types are executed asynchronously.
 func (h *RefreshHandler) issueNewTokens(ctx context.Context, req TokenRequest, resp Response) error {
-	errChan := make(chan error, 1)
-	defer close(errChan)
-
-	wg := new(sync.WaitGroup)
-	wg.Add(2)
-	doAsync(ctx, wg, errChan, func() error {
-		return h.AccessTokenHelper.GenToken(ctx, req, resp)
-	})
-	doAsync(ctx, wg, errChan, func() error {
-		return h.RefreshTokenHelper.GenToken(ctx, req, resp)
-	})
-	wg.Wait()
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case err := <-errChan:
-		return err
-	default:
-		return nil
-	}
+	return doAll(
+		func() error {
+			return h.AccessTokenHelper.GenToken(ctx, req, resp)
+		},
+		func() error {
+			return h.RefreshTokenHelper.GenToken(ctx, req, resp)
+		},
+	)
 }
 
 func (h *RefreshHandler) SupportsTokenRequest(req TokenRequest) bool {
 	return V(req.GetGrantTypes()).ContainsExactly(spi.GrantTypeRefresh)
 }
+
+func (h *RefreshHandler) SupportedGrantTypes() []string {
+	return []string{spi.GrantTypeRefresh}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/imulab-z/platform-sdk/spi"
 	"github.com/stretchr/testify/suite"
+	"sync"
 	"testing"
 	"time"
 )
@@ -69,6 +70,164 @@ func (s *RefreshHandlerTestSuite) TestRefreshToken() {
 	s.Assert().NotEmpty(resp.GetString(AccessToken))
 }
 
+func TestRefreshHandlerRotation(t *testing.T) {
+	s := new(RefreshHandlerRotationTestSuite)
+	suite.Run(t, s)
+}
+
+type RefreshHandlerRotationTestSuite struct {
+	suite.Suite
+	h           *RefreshHandler
+	accessRepo  *inMemTokenRepo
+	refreshRepo *inMemTokenRepo
+}
+
+func (s *RefreshHandlerRotationTestSuite) SetupTest() {
+	kid := "0E0C55C1-2D0A-4C1B-9A57-3F1C4B6E2A10"
+	s.accessRepo = &inMemTokenRepo{}
+	s.refreshRepo = &inMemTokenRepo{}
+	refreshStrategy := NewHmacShaRefreshTokenStrategy(32, MustHmacSha256Strategy())
+	s.h = &RefreshHandler{
+		AccessTokenHelper: &AccessTokenHelper{
+			Lifespan: 30 * time.Minute,
+			Repo:     s.accessRepo,
+			Strategy: NewRs256JwtAccessTokenStrategy("test", 30*time.Minute, MustNewJwksWithRsaKeyForSigning(kid), kid),
+			Mode:     PersistSync,
+		},
+		RefreshTokenHelper:   &RefreshTokenHelper{Repo: s.refreshRepo, Strategy: refreshStrategy, Mode: PersistSync},
+		AccessTokenRepo:      s.accessRepo,
+		RefreshTokenRepo:     s.refreshRepo,
+		RefreshTokenStrategy: refreshStrategy,
+		RotationRepo:         &inMemRotationRepo{},
+	}
+}
+
+func (s *RefreshHandlerRotationTestSuite) TestReuseRevokesFamily() {
+	t0 := s.initialRefreshToken()
+
+	t1, err := s.refresh(t0)
+	s.Require().Nil(err)
+	t2, err := s.refresh(t1)
+	s.Require().Nil(err)
+
+	_, err = s.refresh(t0)
+	s.Require().NotNil(err)
+	s.Assert().Equal("invalid_grant", err.(*spi.OAuthError).Err)
+
+	for _, tok := range []string{t0, t1, t2} {
+		_, err := s.refreshRepo.GetRequest(context.Background(), tok)
+		s.Assert().NotNil(err)
+	}
+	s.Assert().Empty(s.accessRepo.store)
+}
+
+func (s *RefreshHandlerRotationTestSuite) TestReuseWithinGracePeriod() {
+	s.h.ReuseGracePeriod = time.Minute
+	t0 := s.initialRefreshToken()
+
+	t1, err := s.refresh(t0)
+	s.Require().Nil(err)
+	t1b, err := s.refresh(t0)
+	s.Require().Nil(err)
+
+	for _, tok := range []string{t1, t1b} {
+		_, err := s.refreshRepo.GetRequest(context.Background(), tok)
+		s.Assert().Nil(err)
+	}
+}
+
+func (s *RefreshHandlerRotationTestSuite) TestConcurrentReuse() {
+	t0 := s.initialRefreshToken()
+
+	// both refreshes pass reuse detection before either of them rotates
+	first, err := s.updateSession(t0)
+	s.Require().Nil(err)
+	second, err := s.updateSession(t0)
+	s.Require().Nil(err)
+
+	t1, err := s.issue(first)
+	s.Require().Nil(err)
+
+	_, err = s.issue(second)
+	s.Require().NotNil(err)
+	s.Assert().Equal("invalid_grant", err.(*spi.OAuthError).Err)
+
+	for _, tok := range []string{t0, t1} {
+		_, err := s.refreshRepo.GetRequest(context.Background(), tok)
+		s.Assert().NotNil(err)
+	}
+}
+
+func (s *RefreshHandlerRotationTestSuite) initialRefreshToken() string {
+	req := NewAuthorizeRequest()
+	req.SetClient(&refreshHandlerTestSuiteClient{})
+	req.GetSession().SetSubject("test user")
+	req.GetSession().AddGrantedScopes(spi.ScopeOfflineAccess)
+
+	resp := NewResponse()
+	s.Require().Nil(s.h.RefreshTokenHelper.GenToken(context.Background(), req, resp))
+	return resp.GetString(RefreshToken)
+}
+
+func (s *RefreshHandlerRotationTestSuite) refresh(token string) (string, error) {
+	req, err := s.updateSession(token)
+	if err != nil {
+		return "", err
+	}
+	return s.issue(req)
+}
+
+func (s *RefreshHandlerRotationTestSuite) updateSession(token string) (TokenRequest, error) {
+	req := NewTokenRequest()
+	req.SetClient(&refreshHandlerTestSuiteClient{})
+	req.AddGrantTypes(spi.GrantTypeRefresh)
+	req.SetRefreshToken(token)
+
+	if err := s.h.UpdateSession(context.Background(), req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (s *RefreshHandlerRotationTestSuite) issue(req TokenRequest) (string, error) {
+	resp := NewResponse()
+	if err := s.h.IssueToken(context.Background(), req, resp); err != nil {
+		return "", err
+	}
+	return resp.GetString(RefreshToken), nil
+}
+
+// support: RefreshTokenRotationRepository
+type inMemRotationRepo struct {
+	sync.Mutex
+	rotations []*RefreshTokenRotation
+}
+
+func (r *inMemRotationRepo) SaveRotation(ctx context.Context, rotation *RefreshTokenRotation) (
+	[]*RefreshTokenRotation, error) {
+	r.Lock()
+	defer r.Unlock()
+	previous := r.findLocked(rotation.ParentRequestId)
+	r.rotations = append(r.rotations, rotation)
+	return previous, nil
+}
+
+func (r *inMemRotationRepo) GetRotations(ctx context.Context, parentRequestId string) ([]*RefreshTokenRotation, error) {
+	r.Lock()
+	defer r.Unlock()
+	return r.findLocked(parentRequestId), nil
+}
+
+func (r *inMemRotationRepo) findLocked(parentRequestId string) []*RefreshTokenRotation {
+	found := make([]*RefreshTokenRotation, 0)
+	for _, rotation := range r.rotations {
+		if rotation.ParentRequestId == parentRequestId {
+			found = append(found, rotation)
+		}
+	}
+	return found
+}
+
 // support: AccessTokenRepository
 type refreshHandlerTestSuiteAccessTokenRepo struct {
 	*NoOpAccessTokenRepo
@@ -87,6 +246,7 @@ type refreshHandlerTestSuiteRefreshTokenRepo struct {
 func (r *refreshHandlerTestSuiteRefreshTokenRepo) GetRequest(ctx context.Context, token string) (Request, error) {
 	req := NewTokenRequest()
 	req.SetId("old_request")
+	req.SetClient(&refreshHandlerTestSuiteClient{})
 	req.GetSession().SetSubject("test user")
 	req.GetSession().AddGrantedScopes("foo", "bar")
 	return req, nil
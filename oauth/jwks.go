@@ -54,6 +54,17 @@ func FindVerificationKeyByAlg(jwks *jose.JSONWebKeySet, alg string) *jose.JSONWe
 	return nil
 }
 
+// Returns the key with private parts for the algorithm which may be used to decrypt content encrypted to the server.
+// Keys designated for signature are skipped.
+func FindDecryptionKeyByAlg(jwks *jose.JSONWebKeySet, alg string) *jose.JSONWebKey {
+	for _, jwk := range jwks.Keys {
+		if jwk.Algorithm == alg && jwk.Use != "sig" && jwk.Use != "sign" {
+			return &jwk
+		}
+	}
+	return nil
+}
+
 func FindEncryptionKeyByAlg(jwks *jose.JSONWebKeySet, alg string) *jose.JSONWebKey {
 	for _, jwk := range jwks.Keys {
 		if jwk.Algorithm == alg {
@@ -0,0 +1,104 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/imulab-z/platform-sdk/spi"
+	"gopkg.in/square/go-jose.v2"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Returns a json web key set containing only the public parts of the keys in the given set, so that it can be
+// published. Every key must specify kid, use and alg. Symmetric keys are refused, as they cannot be published without
+// disclosing the secret.
+//
+// This method is exposed to reduce the work of publishing keys through custom channels.
+func PublicJwks(jwks *jose.JSONWebKeySet) (*jose.JSONWebKeySet, error) {
+	public := &jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, 0, len(jwks.Keys))}
+
+	for _, jwk := range jwks.Keys {
+		if len(jwk.KeyID) == 0 || len(jwk.Use) == 0 || len(jwk.Algorithm) == 0 {
+			return nil, fmt.Errorf("key %s must specify kid, use and alg to be published", jwk.KeyID)
+		}
+
+		if _, ok := jwk.Key.([]byte); ok {
+			return nil, fmt.Errorf("symmetric key %s must not be published", jwk.KeyID)
+		}
+
+		pk := jwk.Public()
+		if !pk.Valid() {
+			return nil, fmt.Errorf("key %s has no valid public part", jwk.KeyID)
+		}
+		public.Keys = append(public.Keys, pk)
+	}
+
+	return public, nil
+}
+
+// Serves the public parts of the server json web key set. Responses are cacheable for MaxAge and carry an ETag derived
+// from the content, so that relying parties can poll with If-None-Match and receive 304 Not Modified until keys change.
+// When KeySource (i.e. KeyManager) is set, its key set is published, so that upcoming and retiring keys are published
+// along with the active key. Keys in Jwks are published alongside for the algorithms KeySource has no key for, as
+// signers fall back to them (see FindSigningKey).
+type JwksHandler struct {
+	Jwks      *jose.JSONWebKeySet
+	KeySource SigningKeySource
+	MaxAge    time.Duration
+}
+
+func (h *JwksHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodHead}, ", "))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	public, err := PublicJwks(PublishedJwks(h.KeySource, h.Jwks))
+	if err != nil {
+		_ = writeJsonError(w, spi.ErrServerError(err))
+		return
+	}
+
+	raw, err := json.Marshal(public)
+	if err != nil {
+		_ = writeJsonError(w, spi.ErrServerError(err))
+		return
+	}
+
+	sum := sha256.Sum256(raw)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(h.MaxAge/time.Second)))
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(raw)
+	}
+}
+
+// Returns true if the If-None-Match header value matches the etag, using the weak comparison defined in RFC 7232.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if len(ifNoneMatch) == 0 {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package oauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublicJwks(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	for _, v := range []struct {
+		name        string
+		keys        []jose.JSONWebKey
+		expectError bool
+	}{
+		{
+			name: "asymmetric signing and encryption keys",
+			keys: []jose.JSONWebKey{
+				{Key: rsaKey, KeyID: "rsa", Use: "sig", Algorithm: string(jose.RS256)},
+				{Key: ecKey, KeyID: "ec", Use: "enc", Algorithm: string(jose.ECDH_ES)},
+			},
+			expectError: false,
+		},
+		{
+			name: "symmetric key",
+			keys: []jose.JSONWebKey{
+				{Key: []byte("secret"), KeyID: "hmac", Use: "sig", Algorithm: string(jose.HS256)},
+			},
+			expectError: true,
+		},
+		{
+			name: "missing use",
+			keys: []jose.JSONWebKey{
+				{Key: rsaKey, KeyID: "rsa", Algorithm: string(jose.RS256)},
+			},
+			expectError: true,
+		},
+	} {
+		public, err := PublicJwks(&jose.JSONWebKeySet{Keys: v.keys})
+		if v.expectError {
+			assert.NotNil(t, err, v.name)
+			continue
+		}
+
+		assert.Nil(t, err, v.name)
+		assert.Len(t, public.Keys, len(v.keys), v.name)
+		for _, k := range public.Keys {
+			assert.True(t, k.IsPublic(), v.name)
+		}
+	}
+}
+
+func TestJwksHandler(t *testing.T) {
+	h := &JwksHandler{Jwks: MustNewJwksWithRsaKeyForSigning("test-key"), MaxAge: 10 * time.Minute}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/jwks.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=600", w.Header().Get("Cache-Control"))
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	body := make(map[string][]map[string]interface{})
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Len(t, body["keys"], 1)
+	assert.Equal(t, "test-key", body["keys"][0]["kid"])
+	assert.Nil(t, body["keys"][0]["d"])
+
+	r := httptest.NewRequest(http.MethodGet, "/jwks.json", nil)
+	r.Header.Set("If-None-Match", `"stale", W/`+etag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+}
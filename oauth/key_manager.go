@@ -0,0 +1,255 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/square/go-jose.v2"
+	"sync"
+	"time"
+)
+
+// Key states
+const (
+	// Key that will become active on the next rotation. It is published in advance so that relying parties learn
+	// about it before any token is signed with it.
+	KeyStateNext = "next"
+	// Key used for signing.
+	KeyStateActive = "active"
+	// Previously active key. It is no longer used for signing, but still published and accepted for verification
+	// until the retirement period elapses.
+	KeyStateRetiring = "retiring"
+	// Key that is neither published nor accepted for verification anymore.
+	KeyStateRetired = "retired"
+)
+
+// Source of signing and verification keys which may change over time.
+type SigningKeySource interface {
+	// Returns the key to sign with for the algorithm, or nil if no key is available for the algorithm.
+	SigningKey(alg string) *jose.JSONWebKey
+	// Returns the key identified by kid to verify with, or nil if the key is unknown or retired.
+	VerificationKey(kid string) *jose.JSONWebKey
+	// Returns the json web key set to be published. Keys may still contain private parts, see PublicJwks.
+	Jwks() *jose.JSONWebKeySet
+}
+
+// Returns the key to sign with for the algorithm from source, falling back to the key registered for the algorithm in
+// jwks when source is nil or has no key for it, so that a KeySource rotating keys of one algorithm can be complemented
+// by static keys of other algorithms. Returns nil if neither has a key for the algorithm.
+func FindSigningKey(source SigningKeySource, jwks *jose.JSONWebKeySet, alg string) *jose.JSONWebKey {
+	if source != nil {
+		if key := source.SigningKey(alg); key != nil {
+			return key
+		}
+	}
+	if jwks == nil {
+		return nil
+	}
+	return FindSigningKeyByAlg(jwks, alg)
+}
+
+// Returns the json web key set to be published when signing through FindSigningKey: the key set of source, along with
+// the keys of jwks whose algorithm source has no key for. Either may be nil. Keys may still contain private parts, see
+// PublicJwks.
+func PublishedJwks(source SigningKeySource, jwks *jose.JSONWebKeySet) *jose.JSONWebKeySet {
+	if source == nil {
+		if jwks == nil {
+			return &jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, 0)}
+		}
+		return jwks
+	}
+
+	published := &jose.JSONWebKeySet{Keys: append([]jose.JSONWebKey{}, source.Jwks().Keys...)}
+	if jwks != nil {
+		for _, key := range jwks.Keys {
+			if source.SigningKey(key.Algorithm) == nil {
+				published.Keys = append(published.Keys, key)
+			}
+		}
+	}
+	return published
+}
+
+// Function to generate a fresh key. The key must carry kid, use and alg.
+type KeyGenerator func() (jose.JSONWebKey, error)
+
+// Returns a KeyGenerator which generates RSA keys of the given size for the signature algorithm. Key ids are random
+// UUIDs.
+func NewRsaKeyGenerator(alg jose.SignatureAlgorithm, bits int) KeyGenerator {
+	return func() (jose.JSONWebKey, error) {
+		privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return jose.JSONWebKey{}, err
+		}
+		return jose.JSONWebKey{
+			Key:       privateKey,
+			KeyID:     uuid.NewV4().String(),
+			Algorithm: string(alg),
+			Use:       "sig",
+		}, nil
+	}
+}
+
+// Snapshot of a key managed by KeyManager.
+type ManagedKey struct {
+	Key   jose.JSONWebKey
+	State string
+	// Time after which a retiring key is retired, zero for keys in other states.
+	RetiresAt time.Time
+}
+
+// SigningKeySource which rotates keys through the next, active, retiring and retired states. On every rotation, the
+// active key becomes retiring, the next key becomes active and a new next key is generated. Retiring keys are retired
+// once RetirementPeriod has elapsed since their rotation, which should be set longer than the lifespan of any token
+// signed with them.
+type KeyManager struct {
+	Generator        KeyGenerator
+	RotationInterval time.Duration
+	RetirementPeriod time.Duration
+
+	mu       sync.RWMutex
+	next     *jose.JSONWebKey
+	active   *jose.JSONWebKey
+	retiring []ManagedKey
+}
+
+// Create a KeyManager with freshly generated active and next keys.
+func NewKeyManager(generator KeyGenerator, rotationInterval time.Duration, retirementPeriod time.Duration) (*KeyManager, error) {
+	m := &KeyManager{
+		Generator:        generator,
+		RotationInterval: rotationInterval,
+		RetirementPeriod: retirementPeriod,
+		retiring:         make([]ManagedKey, 0),
+	}
+
+	active, err := m.generate()
+	if err != nil {
+		return nil, err
+	}
+	next, err := m.generate()
+	if err != nil {
+		return nil, err
+	}
+	m.active, m.next = active, next
+
+	return m, nil
+}
+
+// Rotate keys immediately.
+func (m *KeyManager) Rotate() error {
+	fresh, err := m.generate()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.active != nil {
+		m.retiring = append(m.retiring, ManagedKey{
+			Key:       *m.active,
+			State:     KeyStateRetiring,
+			RetiresAt: time.Now().Add(m.RetirementPeriod),
+		})
+	}
+	m.active, m.next = m.next, fresh
+	m.pruneRetired()
+
+	return nil
+}
+
+// Rotate keys every RotationInterval until the context is cancelled. Failed rotations are logged and retried on the
+// next tick, the current keys remain in use meanwhile.
+func (m *KeyManager) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.RotationInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.Rotate(); err != nil {
+					logrus.WithError(err).Errorln("failed to rotate keys.")
+				}
+			}
+		}
+	}()
+}
+
+func (m *KeyManager) SigningKey(alg string) *jose.JSONWebKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.active == nil || m.active.Algorithm != alg {
+		return nil
+	}
+	key := *m.active
+	return &key
+}
+
+func (m *KeyManager) VerificationKey(kid string) *jose.JSONWebKey {
+	for _, k := range m.Keys() {
+		if k.Key.KeyID == kid && k.State != KeyStateRetired {
+			return FindVerificationKeyById(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{k.Key}}, kid)
+		}
+	}
+	return nil
+}
+
+// Returns the next, active and retiring keys.
+func (m *KeyManager) Jwks() *jose.JSONWebKeySet {
+	jwks := &jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, 0)}
+	for _, k := range m.Keys() {
+		if k.State != KeyStateRetired {
+			jwks.Keys = append(jwks.Keys, k.Key)
+		}
+	}
+	return jwks
+}
+
+// Returns a snapshot of the managed keys in the order of next, active and retiring. Retiring keys whose retirement
+// period has elapsed are reported as retired until they are pruned on the next rotation.
+func (m *KeyManager) Keys() []ManagedKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]ManagedKey, 0, len(m.retiring)+2)
+	if m.next != nil {
+		keys = append(keys, ManagedKey{Key: *m.next, State: KeyStateNext})
+	}
+	if m.active != nil {
+		keys = append(keys, ManagedKey{Key: *m.active, State: KeyStateActive})
+	}
+	for _, k := range m.retiring {
+		if time.Now().After(k.RetiresAt) {
+			k.State = KeyStateRetired
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (m *KeyManager) pruneRetired() {
+	remaining := make([]ManagedKey, 0, len(m.retiring))
+	for _, k := range m.retiring {
+		if time.Now().Before(k.RetiresAt) {
+			remaining = append(remaining, k)
+		}
+	}
+	m.retiring = remaining
+}
+
+func (m *KeyManager) generate() (*jose.JSONWebKey, error) {
+	key, err := m.Generator()
+	if err != nil {
+		return nil, err
+	}
+	if len(key.KeyID) == 0 || len(key.Algorithm) == 0 {
+		return nil, errors.New("generated key must carry kid and alg")
+	}
+	return &key, nil
+}
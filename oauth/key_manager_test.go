@@ -0,0 +1,109 @@
+package oauth
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/test"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/square/go-jose.v2"
+	"testing"
+	"time"
+)
+
+func TestKeyManager(t *testing.T) {
+	s := new(KeyManagerTestSuite)
+	suite.Run(t, s)
+}
+
+type KeyManagerTestSuite struct {
+	suite.Suite
+	manager *KeyManager
+}
+
+func (s *KeyManagerTestSuite) SetupTest() {
+	m, err := NewKeyManager(NewRsaKeyGenerator(jose.RS256, 2048), time.Hour, time.Hour)
+	s.Require().Nil(err)
+	s.manager = m
+}
+
+func (s *KeyManagerTestSuite) TestInitialState() {
+	keys := s.manager.Keys()
+	s.Require().Len(keys, 2)
+	s.Assert().Equal(KeyStateNext, keys[0].State)
+	s.Assert().Equal(KeyStateActive, keys[1].State)
+
+	s.Assert().Equal(keys[1].Key.KeyID, s.manager.SigningKey(string(jose.RS256)).KeyID)
+	s.Assert().Nil(s.manager.SigningKey(string(jose.ES256)))
+	s.Assert().Len(s.manager.Jwks().Keys, 2)
+}
+
+func (s *KeyManagerTestSuite) TestRotate() {
+	before := s.manager.Keys()
+	s.Require().Nil(s.manager.Rotate())
+	after := s.manager.Keys()
+
+	s.Require().Len(after, 3)
+	s.Assert().NotEqual(before[0].Key.KeyID, after[0].Key.KeyID)
+	s.Assert().Equal(before[0].Key.KeyID, after[1].Key.KeyID)
+	s.Assert().Equal(KeyStateActive, after[1].State)
+	s.Assert().Equal(before[1].Key.KeyID, after[2].Key.KeyID)
+	s.Assert().Equal(KeyStateRetiring, after[2].State)
+
+	// retiring key is still published and accepted for verification
+	s.Assert().Len(s.manager.Jwks().Keys, 3)
+	s.Assert().NotNil(s.manager.VerificationKey(before[1].Key.KeyID))
+	s.Assert().Nil(s.manager.VerificationKey("unknown"))
+}
+
+func (s *KeyManagerTestSuite) TestRetire() {
+	s.manager.RetirementPeriod = 0
+	old := s.manager.SigningKey(string(jose.RS256))
+
+	s.Require().Nil(s.manager.Rotate())
+	s.Assert().Nil(s.manager.VerificationKey(old.KeyID))
+	s.Assert().Len(s.manager.Jwks().Keys, 2)
+	s.Assert().Len(s.manager.Keys(), 2)
+}
+
+func (s *KeyManagerTestSuite) TestFallbackToStaticKeys() {
+	static := &jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{Key: []byte("not published"), KeyID: "static-rs256", Algorithm: string(jose.RS256), Use: "sig"},
+			{Key: []byte("published"), KeyID: "static-ps256", Algorithm: string(jose.PS256), Use: "sig"},
+		},
+	}
+
+	s.Assert().Equal(s.manager.SigningKey(string(jose.RS256)).KeyID, FindSigningKey(s.manager, static, string(jose.RS256)).KeyID)
+	s.Assert().Equal("static-ps256", FindSigningKey(s.manager, static, string(jose.PS256)).KeyID)
+	s.Assert().Nil(FindSigningKey(s.manager, static, string(jose.ES256)))
+	s.Assert().Equal("static-rs256", FindSigningKey(nil, static, string(jose.RS256)).KeyID)
+
+	published := PublishedJwks(s.manager, static)
+	s.Assert().Len(published.Keys, 3)
+	s.Assert().NotEmpty(published.Key("static-ps256"))
+	s.Assert().Empty(published.Key("static-rs256"))
+	s.Assert().Len(s.manager.Jwks().Keys, 2)
+}
+
+func (s *KeyManagerTestSuite) TestAccessTokenAcrossRotation() {
+	strategy := &JwtAccessTokenStrategy{
+		Issuer:        "test",
+		TokenLifespan: 30 * time.Minute,
+		SigningAlg:    jose.RS256,
+		KeySource:     s.manager,
+	}
+
+	req := NewAuthorizeRequest()
+	req.SetClient(new(test.MockClient))
+	req.SetSession(NewSession())
+
+	tok, err := strategy.NewToken(context.Background(), req)
+	s.Require().Nil(err)
+	s.Assert().Nil(strategy.ValidateToken(context.Background(), tok, req))
+
+	s.manager.RetirementPeriod = 50 * time.Millisecond
+	s.Require().Nil(s.manager.Rotate())
+	s.Assert().Nil(strategy.ValidateToken(context.Background(), tok, req))
+
+	time.Sleep(100 * time.Millisecond)
+	s.Assert().NotNil(strategy.ValidateToken(context.Background(), tok, req))
+}
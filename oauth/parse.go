@@ -2,15 +2,22 @@ package oauth
 
 import (
 	"context"
+	"fmt"
 	"github.com/imulab-z/platform-sdk/spi"
 	"github.com/sirupsen/logrus"
+	"mime"
 	"net/http"
 	"net/url"
 	"strings"
 )
 
+const (
+	ContentTypeForm = "application/x-www-form-urlencoded"
+)
+
 var (
-	ErrMethodNotSupported = spi.ErrInvalidRequest("http method is not supported.")
+	ErrMethodNotSupported      = spi.ErrInvalidRequest("http method is not supported.")
+	ErrContentTypeNotSupported = spi.ErrInvalidRequest("content type is not supported.")
 )
 
 type RequestParser interface {
@@ -18,15 +25,41 @@ type RequestParser interface {
 	ParseTokenRequest(ctx context.Context, r *http.Request, req TokenRequest) error
 }
 
-func NewHttpRequestParser(lookup spi.ClientLookup) RequestParser {
-	return &httpRequestParser{ClientLookup:lookup}
+// Resolves the effective authorization parameters before they are parsed into the request, i.e. by expanding the
+// request object supplied in the request or request_uri parameter. The client is the one identified by the client_id
+// query parameter. Implementations shall return the values as is when there is nothing to resolve.
+type AuthorizeParameterResolver interface {
+	ResolveAuthorizeParameters(ctx context.Context, client spi.OAuthClient, values url.Values) (url.Values, error)
+}
+
+// Create a RequestParser. The resolvers are invoked in order on authorize requests, each receiving the output of the
+// previous one.
+func NewHttpRequestParser(lookup spi.ClientLookup, authentication ClientAuthentication,
+	resolvers ...AuthorizeParameterResolver) RequestParser {
+	return &httpRequestParser{ClientLookup:lookup, ClientAuthentication:authentication, Resolvers:resolvers}
 }
 
 type httpRequestParser struct {
-	ClientLookup	spi.ClientLookup
+	ClientLookup			spi.ClientLookup
+	ClientAuthentication	ClientAuthentication
+	Resolvers				[]AuthorizeParameterResolver
 }
 
 func (p *httpRequestParser) ParseAuthorizeRequest(ctx context.Context, r *http.Request, req AuthorizeRequest) error {
+	values, err := ReadAuthorizeParameters(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = ParseAuthorizeParameters(ctx, p.ClientLookup, p.Resolvers, values, req)
+	return err
+}
+
+// Reads the authorization parameters from the query of a GET request, or the form of a POST request. Returns an
+// invalid_request error if the parameters are malformed or repeated.
+//
+// This method is exposed to reduce the work of extending the parser.
+func ReadAuthorizeParameters(r *http.Request) (url.Values, error) {
 	var (
 		values 	url.Values
 		err 	error
@@ -34,18 +67,42 @@ func (p *httpRequestParser) ParseAuthorizeRequest(ctx context.Context, r *http.R
 
 	switch r.Method {
 	case http.MethodGet:
-		values, err = url.ParseQuery(r.URL.RawQuery)
-		if err != nil {
-			return err
+		if values, err = url.ParseQuery(r.URL.RawQuery); err != nil {
+			return nil, spi.ErrInvalidRequest(err.Error())
 		}
+	case http.MethodPost:
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != ContentTypeForm {
+			return nil, ErrContentTypeNotSupported
+		}
+		if err = r.ParseForm(); err != nil {
+			return nil, spi.ErrInvalidRequest(err.Error())
+		}
+		values = r.PostForm
 	default:
-		return ErrMethodNotSupported
+		return nil, ErrMethodNotSupported
+	}
+
+	if err := checkRepeatedParameters(values); err != nil {
+		return nil, err
 	}
 
+	return values, nil
+}
+
+// Parses the OAuth 2.0 authorization parameters into the request: the client is found through the lookup by client_id,
+// then the values are run through the resolvers before response_type, scope, state, PKCE parameters and redirect_uri
+// are read. Returns the resolved values, so that extensions (i.e. Open ID Connect) can read their own parameters from
+// the same source.
+//
+// This method is exposed to reduce the work of extending the parser.
+func ParseAuthorizeParameters(ctx context.Context, lookup spi.ClientLookup, resolvers []AuthorizeParameterResolver,
+	values url.Values, req AuthorizeRequest) (url.Values, error) {
+	p := &httpRequestParser{ClientLookup: lookup, Resolvers: resolvers}
 	return p.parseAuthorizeRequest(ctx, values, req)
 }
 
-func (p *httpRequestParser) parseAuthorizeRequest(ctx context.Context, values url.Values, req AuthorizeRequest) error {
+func (p *httpRequestParser) parseAuthorizeRequest(ctx context.Context, values url.Values, req AuthorizeRequest) (
+	url.Values, error) {
 	logrus.WithFields(logrus.Fields{
 		spi.ParamClientId: values.Get(spi.ParamClientId),
 		spi.ParamResponseType: values.Get(spi.ParamResponseType),
@@ -71,30 +128,132 @@ func (p *httpRequestParser) parseAuthorizeRequest(ctx context.Context, values ur
 		}
 	}()
 
-	req.AddResponseTypes(strings.Split(values.Get(spi.ParamResponseType), " ")...)
-	req.AddScopes(strings.Split(values.Get(spi.ParamScope), " ")...)
-	req.SetState(values.Get(spi.ParamState))
-
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
+		return nil, ctx.Err()
 	case err := <-errChan:
-		return err
+		return nil, err
 	case c := <-clientChan:
 		req.SetClient(c)
 	}
 
+	values, err := p.resolveAuthorizeParameters(ctx, req.GetClient(), values)
+	if err != nil {
+		return nil, err
+	}
+
+	req.AddResponseTypes(strings.Split(values.Get(spi.ParamResponseType), " ")...)
+	req.AddScopes(strings.Split(values.Get(spi.ParamScope), " ")...)
+	req.SetState(values.Get(spi.ParamState))
+	req.SetCodeChallenge(values.Get(spi.ParamCodeChallenge))
+	req.SetCodeChallengeMethod(values.Get(spi.ParamCodeChallengeMethod))
+
 	if uri, err := SelectRedirectUri(values.Get(spi.ParamRedirectUri), req.GetClient().GetRedirectUris()); err != nil {
-		return err
+		return nil, err
 	} else {
 		req.SetRedirectUri(uri)
 	}
 
-	return nil
+	return values, nil
+}
+
+// Runs the values through the resolvers. Without any resolver, request and request_uri parameters are refused, as
+// silently ignoring them would process a request different from what the client intended.
+func (p *httpRequestParser) resolveAuthorizeParameters(ctx context.Context, client spi.OAuthClient, values url.Values) (
+	url.Values, error) {
+	if len(p.Resolvers) == 0 {
+		switch {
+		case len(values.Get(spi.ParamRequest)) > 0:
+			return nil, spi.ErrRequestNotSupported()
+		case len(values.Get(spi.ParamRequestUri)) > 0:
+			return nil, spi.ErrRequestUriNotSupported()
+		default:
+			return values, nil
+		}
+	}
+
+	var err error
+	for _, resolver := range p.Resolvers {
+		if values, err = resolver.ResolveAuthorizeParameters(ctx, client, values); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
 }
 
 func (p *httpRequestParser) ParseTokenRequest(ctx context.Context, r *http.Request, req TokenRequest) error {
-	panic("implement me")
+	if client, values, err := ParseAuthenticatedForm(ctx, r, p.ClientAuthentication); err != nil {
+		return err
+	} else {
+		req.SetClient(client)
+		return p.parseTokenRequest(ctx, values, req)
+	}
+}
+
+func (p *httpRequestParser) parseTokenRequest(ctx context.Context, values url.Values, req TokenRequest) error {
+	logrus.WithFields(logrus.Fields{
+		spi.ParamClientId: req.GetClient().GetId(),
+		spi.ParamGrantType: values.Get(spi.ParamGrantType),
+		spi.ParamRedirectUri: values.Get(spi.ParamRedirectUri),
+		spi.ParamScope: values.Get(spi.ParamScope),
+	}).Debug("received token request.")
+
+	if grantType := values.Get(spi.ParamGrantType); len(grantType) > 0 {
+		req.AddGrantTypes(grantType)
+	}
+
+	if scope := values.Get(spi.ParamScope); len(scope) > 0 {
+		req.AddScopes(strings.Split(scope, " ")...)
+	}
+
+	req.SetCode(values.Get(spi.ParamCode))
+	req.SetRefreshToken(values.Get(spi.ParamRefreshToken))
+	req.SetCodeVerifier(values.Get(spi.ParamCodeVerifier))
+	req.SetUsername(values.Get(spi.ParamUsername))
+	req.SetPassword(values.Get(spi.ParamPassword))
+	req.SetRedirectUri(values.Get(spi.ParamRedirectUri))
+
+	return nil
 }
 
+// Reads the form parameters of a POST request and authenticates the calling client. This is the common preamble shared
+// by endpoints which require client authentication, i.e. the token, introspection and revocation endpoints.
+//
+// This method is exposed to reduce the work of rolling out custom endpoints.
+func ParseAuthenticatedForm(ctx context.Context, r *http.Request, authentication ClientAuthentication) (
+	spi.OAuthClient, url.Values, error) {
+	if r.Method != http.MethodPost {
+		return nil, nil, ErrMethodNotSupported
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != ContentTypeForm {
+		return nil, nil, ErrContentTypeNotSupported
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, nil, spi.ErrInvalidRequest(err.Error())
+	}
+
+	if err := checkRepeatedParameters(r.PostForm); err != nil {
+		return nil, nil, err
+	}
+
+	client, err := authentication.Authenticate(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return client, r.PostForm, nil
+}
+
+// Returns an invalid_request error if any of the parameters were supplied more than once.
+func checkRepeatedParameters(values url.Values) error {
+	for k, v := range values {
+		if len(v) > 1 {
+			return spi.ErrInvalidRequest(fmt.Sprintf("parameter %s is repeated.", k))
+		}
+	}
+	return nil
+}
 
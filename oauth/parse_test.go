@@ -9,6 +9,8 @@ import (
 	"github.com/stretchr/testify/suite"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -33,6 +35,7 @@ func (s *HttpRequestParserTestSuite) SetupTest() {
 
 	s.parser = &httpRequestParser{
 		ClientLookup: lookup,
+		ClientAuthentication: &parserTestClientAuthentication{client: client},
 	}
 }
 
@@ -59,3 +62,162 @@ func (s *HttpRequestParserTestSuite) TestParseAuthorizeRequest() {
 	s.Assert().Contains(req.GetResponseTypes(), spi.ResponseTypeCode)
 	s.Assert().Equal("https://mock.test.org/callback", req.GetRedirectUri())
 }
+
+func (s *HttpRequestParserTestSuite) TestParseAuthorizeRequestWithRequestObject() {
+	r := httptest.NewRequest(
+		http.MethodGet,
+		fmt.Sprintf("http://test.org/oauth/authorize?client_id=%s&request=%s", s.clientId, "some-request-object"),
+		nil,
+	)
+
+	err := s.parser.ParseAuthorizeRequest(context.Background(), r, NewAuthorizeRequest())
+	s.Assert().NotNil(err)
+	s.Assert().Equal("request_not_supported", err.(*spi.OAuthError).Err)
+
+	s.parser.Resolvers = []AuthorizeParameterResolver{&parserTestResolver{
+		resolved: url.Values{
+			spi.ParamClientId:     {s.clientId},
+			spi.ParamResponseType: {spi.ResponseTypeCode},
+			spi.ParamRedirectUri:  {"https://mock.test.org/callback"},
+			spi.ParamState:        {"12345678"},
+		},
+	}}
+	req := NewAuthorizeRequest()
+	err = s.parser.ParseAuthorizeRequest(context.Background(), r, req)
+	s.Assert().Nil(err)
+	s.Assert().Contains(req.GetResponseTypes(), spi.ResponseTypeCode)
+	s.Assert().Equal("12345678", req.GetState())
+}
+
+func (s *HttpRequestParserTestSuite) TestParseTokenRequest() {
+	for _, v := range []struct {
+		name        string
+		reqFunc     func() *http.Request
+		expectError string
+		assertion   func(req TokenRequest)
+	}{
+		{
+			name: "authorization code request",
+			reqFunc: func() *http.Request {
+				f := url.Values{}
+				f.Set(spi.ParamClientId, s.clientId)
+				f.Set(spi.ParamGrantType, spi.GrantTypeCode)
+				f.Set(spi.ParamCode, "some-code")
+				f.Set(spi.ParamRedirectUri, "https://mock.test.org/callback")
+				r := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(f.Encode()))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return r
+			},
+			expectError: "",
+			assertion: func(req TokenRequest) {
+				s.Assert().Equal(s.clientId, req.GetClient().GetId())
+				s.Assert().Equal([]string{spi.GrantTypeCode}, req.GetGrantTypes())
+				s.Assert().Equal("some-code", req.GetCode())
+				s.Assert().Equal("https://mock.test.org/callback", req.GetRedirectUri())
+			},
+		},
+		{
+			name: "refresh token request",
+			reqFunc: func() *http.Request {
+				f := url.Values{}
+				f.Set(spi.ParamClientId, s.clientId)
+				f.Set(spi.ParamGrantType, spi.GrantTypeRefresh)
+				f.Set(spi.ParamRefreshToken, "some-token")
+				f.Set(spi.ParamScope, "foo bar")
+				r := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(f.Encode()))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+				return r
+			},
+			expectError: "",
+			assertion: func(req TokenRequest) {
+				s.Assert().Equal([]string{spi.GrantTypeRefresh}, req.GetGrantTypes())
+				s.Assert().Equal("some-token", req.GetRefreshToken())
+				s.Assert().Equal([]string{"foo", "bar"}, req.GetScopes())
+			},
+		},
+		{
+			name: "wrong http method",
+			reqFunc: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/oauth/token?grant_type=authorization_code", nil)
+			},
+			expectError: "invalid_request",
+		},
+		{
+			name: "wrong content type",
+			reqFunc: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(`{"grant_type":"authorization_code"}`))
+				r.Header.Set("Content-Type", "application/json")
+				return r
+			},
+			expectError: "invalid_request",
+		},
+		{
+			name: "repeated parameter",
+			reqFunc: func() *http.Request {
+				f := url.Values{}
+				f.Set(spi.ParamClientId, s.clientId)
+				f.Add(spi.ParamGrantType, spi.GrantTypeCode)
+				f.Add(spi.ParamGrantType, spi.GrantTypeRefresh)
+				r := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(f.Encode()))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return r
+			},
+			expectError: "invalid_request",
+		},
+		{
+			name: "failed client authentication",
+			reqFunc: func() *http.Request {
+				f := url.Values{}
+				f.Set(spi.ParamClientId, "foo")
+				f.Set(spi.ParamGrantType, spi.GrantTypeCode)
+				r := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(f.Encode()))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return r
+			},
+			expectError: "invalid_client",
+		},
+	} {
+		req := NewTokenRequest()
+		err := s.parser.ParseTokenRequest(context.Background(), v.reqFunc(), req)
+		if len(v.expectError) > 0 {
+			s.Assert().NotNil(err, v.name)
+			s.Assert().Equal(v.expectError, err.(*spi.OAuthError).Err, v.name)
+		} else {
+			s.Assert().Nil(err, v.name)
+			v.assertion(req)
+		}
+	}
+}
+
+// support: ClientAuthentication
+type parserTestClientAuthentication struct {
+	client spi.OAuthClient
+}
+
+func (a *parserTestClientAuthentication) Authenticate(ctx context.Context, r *http.Request) (spi.OAuthClient, error) {
+	if r.PostForm.Get(spi.ParamClientId) != a.client.GetId() {
+		return nil, spi.ErrInvalidClient("authentication failed", "")
+	}
+	return a.client, nil
+}
+
+func (a *parserTestClientAuthentication) Method() string {
+	return spi.AuthMethodNone
+}
+
+func (a *parserTestClientAuthentication) Supports(r *http.Request) bool {
+	return true
+}
+
+// support: AuthorizeParameterResolver
+type parserTestResolver struct {
+	resolved url.Values
+}
+
+func (r *parserTestResolver) ResolveAuthorizeParameters(ctx context.Context, client spi.OAuthClient, values url.Values) (
+	url.Values, error) {
+	if len(values.Get(spi.ParamRequest)) == 0 {
+		return values, nil
+	}
+	return r.resolved, nil
+}
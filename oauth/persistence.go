@@ -0,0 +1,97 @@
+package oauth
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/sirupsen/logrus"
+)
+
+// Determines when token helpers persist the issued tokens.
+type PersistenceMode int
+
+const (
+	// Tokens are saved in the background after being returned. Save failures are only logged, hence a client may
+	// receive a token that was never persisted. This is the default.
+	PersistAsync PersistenceMode = iota
+	// Tokens are saved before being returned, using the request context, so that they take part in any transaction it
+	// carries. Save failures fail the request.
+	PersistSync
+)
+
+// Returns true if persistence operations must be performed one after another, which is the case when the context carries
+// a transaction, or any of the modes is PersistSync.
+func persistSequentially(ctx context.Context, modes ...PersistenceMode) bool {
+	if _, ok := spi.TransactionFrom(ctx); ok {
+		return true
+	}
+	for _, mode := range modes {
+		if mode == PersistSync {
+			return true
+		}
+	}
+	return false
+}
+
+// Runs the action within a transaction started by the manager, if any. The transaction is committed when the action
+// succeeds and rolled back otherwise. When manager is nil, the action is simply invoked with the given context.
+func runInTransaction(ctx context.Context, manager spi.TransactionManager, action func(ctx context.Context) error) error {
+	if manager == nil {
+		return performAfterRollback(ctx, action(ctx))
+	}
+
+	txCtx, tx, err := manager.Begin(ctx)
+	if err != nil {
+		return spi.AsOAuthError(err)
+	}
+
+	if err := action(txCtx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": rbErr,
+			}).Errorln("failed to rollback transaction.")
+		}
+		return performAfterRollback(ctx, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return spi.AsOAuthError(err)
+	}
+
+	return nil
+}
+
+// Error refusing the request, along with an action which must take effect even though the transaction of the request is
+// rolled back, such as revoking tokens upon detection of a replay.
+type afterRollbackError struct {
+	err    error
+	action func(ctx context.Context) error
+}
+
+func (e *afterRollbackError) Error() string {
+	return e.err.Error()
+}
+
+// Returns err after having the action performed outside of any transaction. When the context carries no transaction,
+// the action is performed right away. Otherwise, it is performed by runInTransaction once the transaction has been
+// rolled back, so that it neither contends with nor is discarded along with the transaction.
+func failAfterRollback(ctx context.Context, err error, action func(ctx context.Context) error) error {
+	if _, ok := spi.TransactionFrom(ctx); ok {
+		return &afterRollbackError{err: err, action: action}
+	}
+	return performAfterRollback(ctx, &afterRollbackError{err: err, action: action})
+}
+
+// Performs the action carried by an afterRollbackError, and returns its error, or the failure of the action. Other
+// errors are returned as is.
+func performAfterRollback(ctx context.Context, err error) error {
+	deferred, ok := err.(*afterRollbackError)
+	if !ok {
+		return err
+	}
+
+	if actionErr := deferred.action(spi.WithoutTransaction(ctx)); actionErr != nil {
+		return spi.AsOAuthError(actionErr)
+	}
+
+	return deferred.err
+}
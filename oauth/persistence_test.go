@@ -0,0 +1,183 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+func TestTransactionalPersistence(t *testing.T) {
+	s := new(TransactionalPersistenceTestSuite)
+	suite.Run(t, s)
+}
+
+type TransactionalPersistenceTestSuite struct {
+	suite.Suite
+	manager     *txTestManager
+	accessRepo  *txTestTokenRepo
+	refreshRepo *txTestTokenRepo
+	e           *TokenEndpoint
+}
+
+func (s *TransactionalPersistenceTestSuite) SetupTest() {
+	kid := "5B0B9E43-7D0E-4B55-9E0C-2C8B0F3A6D21"
+	s.manager = &txTestManager{}
+	s.accessRepo = &txTestTokenRepo{}
+	s.refreshRepo = &txTestTokenRepo{}
+	s.e = &TokenEndpoint{
+		Handlers: []TokenHandler{
+			&ClientCredentialsHandler{
+				ScopeComparator: EqualityComparator,
+				AccessTokenHelper: &AccessTokenHelper{
+					Repo:     s.accessRepo,
+					Strategy: NewRs256JwtAccessTokenStrategy("test", 30*time.Minute, MustNewJwksWithRsaKeyForSigning(kid), kid),
+					Lifespan: 30 * time.Minute,
+					Mode:     PersistSync,
+				},
+				RefreshTokenHelper: &RefreshTokenHelper{
+					Repo:     s.refreshRepo,
+					Strategy: NewHmacShaRefreshTokenStrategy(32, MustHmacSha256Strategy()),
+					Mode:     PersistSync,
+				},
+			},
+		},
+		Transactions: s.manager,
+	}
+}
+
+func (s *TransactionalPersistenceTestSuite) TestCommit() {
+	resp, err := s.e.IssueToken(context.Background(), s.newRequest())
+	s.Require().Nil(err)
+
+	s.Assert().True(s.manager.tx.committed)
+	s.Assert().False(s.manager.tx.rolledBack)
+
+	// no waiting: tokens are persisted by the time they are returned
+	_, err = s.accessRepo.GetRequest(context.Background(), resp.GetString(AccessToken))
+	s.Assert().Nil(err)
+	_, err = s.refreshRepo.GetRequest(context.Background(), resp.GetString(RefreshToken))
+	s.Assert().Nil(err)
+}
+
+func (s *TransactionalPersistenceTestSuite) TestRollback() {
+	s.refreshRepo.err = errors.New("connection lost")
+
+	_, err := s.e.IssueToken(context.Background(), s.newRequest())
+	s.Require().NotNil(err)
+	s.Assert().Equal("server_error", err.(*spi.OAuthError).Err)
+
+	s.Assert().False(s.manager.tx.committed)
+	s.Assert().True(s.manager.tx.rolledBack)
+	s.Assert().Empty(s.accessRepo.store)
+}
+
+func (s *TransactionalPersistenceTestSuite) TestCodeDeletedAfterRollbackOnFailure() {
+	codeRepo := &txTestCodeRepo{manager: s.manager}
+	h := &AuthorizeCodeHandler{
+		CodeRepo:          codeRepo,
+		CodeStrategy:      NewHmacShaAuthorizeCodeStrategy(32, MustHmacSha256Strategy()),
+		AccessTokenHelper: &AccessTokenHelper{Mode: PersistSync},
+	}
+
+	req := NewTokenRequest()
+	req.AddGrantTypes(spi.GrantTypeCode)
+	req.SetClient(&clientCredentialsHandlerTestSuiteClient{})
+	req.SetCode("unknown")
+
+	err := runInTransaction(context.Background(), s.manager, func(ctx context.Context) error {
+		return h.UpdateSession(ctx, req)
+	})
+	s.Require().NotNil(err)
+	s.Assert().Equal("invalid_grant", err.(*spi.OAuthError).Err)
+
+	// deletion is synchronous, and performed outside of the transaction once it is rolled back
+	s.Require().Len(codeRepo.deleted, 1)
+	s.Assert().Equal(txTestDeletion{inTransaction: false, afterRollback: true}, codeRepo.deleted[0])
+}
+
+func (s *TransactionalPersistenceTestSuite) newRequest() TokenRequest {
+	req := NewTokenRequest()
+	req.AddGrantTypes(spi.GrantTypeClient)
+	req.AddScopes("foo")
+	req.SetClient(&clientCredentialsHandlerTestSuiteClient{})
+	req.GetSession().AddGrantedScopes(spi.ScopeOfflineAccess)
+	return req
+}
+
+// support: spi.TransactionManager
+type txTestManager struct {
+	tx *txTest
+}
+
+func (m *txTestManager) Begin(ctx context.Context) (context.Context, spi.Transaction, error) {
+	m.tx = &txTest{}
+	return spi.WithTransaction(ctx, m.tx), m.tx, nil
+}
+
+// support: spi.Transaction which defers operations until commit
+type txTest struct {
+	pending    []func()
+	committed  bool
+	rolledBack bool
+}
+
+func (t *txTest) Commit() error {
+	for _, op := range t.pending {
+		op()
+	}
+	t.committed = true
+	return nil
+}
+
+func (t *txTest) Rollback() error {
+	t.pending = nil
+	t.rolledBack = true
+	return nil
+}
+
+// support: AccessTokenRepository and RefreshTokenRepository which enlists in the transaction
+type txTestTokenRepo struct {
+	inMemTokenRepo
+	err error
+}
+
+func (r *txTestTokenRepo) Save(ctx context.Context, token string, req Request) error {
+	if r.err != nil {
+		return r.err
+	}
+	if tx, ok := spi.TransactionFrom(ctx); ok {
+		tx.(*txTest).pending = append(tx.(*txTest).pending, func() {
+			_ = r.inMemTokenRepo.Save(ctx, token, req)
+		})
+		return nil
+	}
+	return r.inMemTokenRepo.Save(ctx, token, req)
+}
+
+// support: AuthorizeCodeRepository which records whether deletions were made within a transaction
+type txTestCodeRepo struct {
+	manager *txTestManager
+	deleted []txTestDeletion
+}
+
+type txTestDeletion struct {
+	inTransaction bool
+	afterRollback bool
+}
+
+func (r *txTestCodeRepo) GetRequest(ctx context.Context, code string) (AuthorizeRequest, error) {
+	return nil, spi.ErrInvalidGrant("authorize code not found.")
+}
+
+func (r *txTestCodeRepo) Save(ctx context.Context, code string, req AuthorizeRequest) error {
+	return nil
+}
+
+func (r *txTestCodeRepo) Delete(ctx context.Context, code string) error {
+	_, inTx := spi.TransactionFrom(ctx)
+	r.deleted = append(r.deleted, txTestDeletion{inTransaction: inTx, afterRollback: r.manager.tx.rolledBack})
+	return nil
+}
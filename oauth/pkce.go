@@ -0,0 +1,77 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"github.com/imulab-z/platform-sdk/spi"
+	"regexp"
+)
+
+// Proof Key for Code Exchange (RFC 7636) support.
+
+var (
+	// code_verifier = 43*128unreserved, code_challenge shares the same syntax.
+	pkceValuePattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+)
+
+// Returns the effective code_challenge_method of the request: when a code_challenge is present but no method is
+// specified, the method defaults to 'plain'.
+func CodeChallengeMethodOf(req AuthorizeRequest) string {
+	if len(req.GetCodeChallenge()) > 0 && len(req.GetCodeChallengeMethod()) == 0 {
+		return spi.CodeChallengeMethodPlain
+	}
+	return req.GetCodeChallengeMethod()
+}
+
+// Returns nil if the code_challenge and code_challenge_method supplied in the authorize request are well formed;
+// otherwise returns an invalid_request error.
+func ValidateCodeChallenge(req AuthorizeRequest) error {
+	if len(req.GetCodeChallenge()) == 0 {
+		if len(req.GetCodeChallengeMethod()) > 0 {
+			return spi.ErrInvalidRequest("code_challenge_method supplied without code_challenge")
+		}
+		return nil
+	}
+
+	switch CodeChallengeMethodOf(req) {
+	case spi.CodeChallengeMethodPlain, spi.CodeChallengeMethodS256:
+	default:
+		return spi.ErrInvalidRequest("unsupported code_challenge_method")
+	}
+
+	if !pkceValuePattern.MatchString(req.GetCodeChallenge()) {
+		return spi.ErrInvalidRequest("malformed code_challenge")
+	}
+
+	return nil
+}
+
+// Returns nil if the code_verifier matches the code_challenge under the code_challenge_method; otherwise returns an
+// invalid_grant error.
+func VerifyCodeVerifier(verifier string, challenge string, method string) error {
+	if len(verifier) == 0 {
+		return spi.ErrInvalidGrant("code_verifier is required")
+	}
+
+	if !pkceValuePattern.MatchString(verifier) {
+		return spi.ErrInvalidGrant("malformed code_verifier")
+	}
+
+	var computed string
+	switch method {
+	case spi.CodeChallengeMethodS256:
+		sum := sha256.Sum256([]byte(verifier))
+		computed = base64.RawURLEncoding.EncodeToString(sum[:])
+	case spi.CodeChallengeMethodPlain, "":
+		computed = verifier
+	default:
+		return spi.ErrInvalidGrant("unsupported code_challenge_method")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) != 1 {
+		return spi.ErrInvalidGrant("code_verifier does not match code_challenge")
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package oauth
+
+import (
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidateCodeChallenge(t *testing.T) {
+	for _, v := range []struct {
+		name        string
+		challenge   string
+		method      string
+		expectError bool
+	}{
+		{name: "no pkce", challenge: "", method: "", expectError: false},
+		{name: "S256", challenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", method: spi.CodeChallengeMethodS256, expectError: false},
+		{name: "default plain", challenge: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk", method: "", expectError: false},
+		{name: "method without challenge", challenge: "", method: spi.CodeChallengeMethodS256, expectError: true},
+		{name: "unknown method", challenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", method: "S512", expectError: true},
+		{name: "short challenge", challenge: "abc", method: spi.CodeChallengeMethodS256, expectError: true},
+	} {
+		req := NewAuthorizeRequest()
+		req.SetCodeChallenge(v.challenge)
+		req.SetCodeChallengeMethod(v.method)
+
+		err := ValidateCodeChallenge(req)
+		if v.expectError {
+			assert.NotNil(t, err, v.name)
+		} else {
+			assert.Nil(t, err, v.name)
+		}
+	}
+}
+
+func TestVerifyCodeVerifier(t *testing.T) {
+	// example from RFC 7636 Appendix B
+	const (
+		verifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+		challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	)
+
+	for _, v := range []struct {
+		name        string
+		verifier    string
+		challenge   string
+		method      string
+		expectError bool
+	}{
+		{name: "S256 match", verifier: verifier, challenge: challenge, method: spi.CodeChallengeMethodS256, expectError: false},
+		{name: "plain match", verifier: verifier, challenge: verifier, method: spi.CodeChallengeMethodPlain, expectError: false},
+		{name: "S256 mismatch", verifier: verifier, challenge: verifier, method: spi.CodeChallengeMethodS256, expectError: true},
+		{name: "plain mismatch", verifier: verifier, challenge: challenge, method: spi.CodeChallengeMethodPlain, expectError: true},
+		{name: "missing verifier", verifier: "", challenge: challenge, method: spi.CodeChallengeMethodS256, expectError: true},
+		{name: "malformed verifier", verifier: "short", challenge: "short", method: spi.CodeChallengeMethodPlain, expectError: true},
+	} {
+		err := VerifyCodeVerifier(v.verifier, v.challenge, v.method)
+		if v.expectError {
+			assert.NotNil(t, err, v.name)
+			assert.Equal(t, "invalid_grant", err.(*spi.OAuthError).Err, v.name)
+		} else {
+			assert.Nil(t, err, v.name)
+		}
+	}
+}
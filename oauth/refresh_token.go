@@ -6,6 +6,7 @@ import (
 	"github.com/imulab-z/platform-sdk/spi"
 	"github.com/sirupsen/logrus"
 	"strings"
+	"time"
 )
 
 type RefreshTokenStrategy interface {
@@ -21,6 +22,24 @@ type RefreshTokenRepository interface {
 	DeleteByRequestId(ctx context.Context, requestId string) error
 }
 
+// Record of a refresh request which rotated the tokens of a parent request into a child request.
+type RefreshTokenRotation struct {
+	ParentRequestId string
+	ChildRequestId  string
+	RotatedAt       time.Time
+}
+
+// Repository for the refresh token families, used by RefreshHandler to detect refresh token reuse. A family is the tree of
+// requests derived from one another through refresh requests.
+type RefreshTokenRotationRepository interface {
+	// Record the rotation, and return the rotations of the same parent recorded before it, as one atomic operation, so
+	// that concurrent refreshes of the parent see each other.
+	SaveRotation(ctx context.Context, rotation *RefreshTokenRotation) ([]*RefreshTokenRotation, error)
+	// Returns the rotations whose parent is the request, or an empty slice if the tokens of the request were never
+	// rotated. More than one rotation exists when the parent was refreshed concurrently within the grace period.
+	GetRotations(ctx context.Context, parentRequestId string) ([]*RefreshTokenRotation, error)
+}
+
 func NewHmacShaRefreshTokenStrategy(entropy uint, hmac crypt.HmacShaStrategy) RefreshTokenStrategy {
 	return &hmacShaRefreshTokenStrategy{entropy: entropy, hmac: hmac}
 }
@@ -59,22 +78,30 @@ func (s *hmacShaRefreshTokenStrategy) ValidateToken(ctx context.Context, token s
 type RefreshTokenHelper struct {
 	Strategy RefreshTokenStrategy
 	Repo     RefreshTokenRepository
+	// Determines whether the token is saved before or after being returned. Defaults to PersistAsync.
+	Mode PersistenceMode
 }
 
 func (h *RefreshTokenHelper) GenToken(ctx context.Context, req Request, resp Response) error {
 	if tok, err := h.Strategy.NewToken(ctx, req); err != nil {
 		return err
 	} else {
-		go func() {
-			if err := h.Repo.Save(context.Background(), tok, req); err != nil {
-				logrus.WithFields(logrus.Fields{
-					"error": err,
-					"token": tok,
-					"request_id": req.GetId(),
-					"client_id": req.GetClient().GetId(),
-				}).Errorln("failed to save refresh token.")
+		if h.Mode == PersistSync {
+			if err := h.Repo.Save(ctx, tok, req); err != nil {
+				return spi.AsOAuthError(err)
 			}
-		}()
+		} else {
+			go func() {
+				if err := h.Repo.Save(context.Background(), tok, req); err != nil {
+					logrus.WithFields(logrus.Fields{
+						"error": err,
+						"token": tok,
+						"request_id": req.GetId(),
+						"client_id": req.GetClient().GetId(),
+					}).Errorln("failed to save refresh token.")
+				}
+			}()
+		}
 		resp.Set(RefreshToken, tok)
 		return nil
 	}
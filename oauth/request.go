@@ -46,13 +46,14 @@ func NewRequest() Request {
 	}
 }
 
+// Client and Session are interfaces, hence are serialized separately by RequestCodec.
 type oauthRequest struct {
 	Id 			string				`json:"id"`
 	Timestamp	int64				`json:"timestamp"`
-	Client 		spi.OAuthClient		`json:"client"`
+	Client 		spi.OAuthClient		`json:"-"`
 	RedirectUri	string				`json:"redirect_uri"`
 	Scopes		[]string			`json:"scopes"`
-	Session 	Session				`json:"session"`
+	Session 	Session				`json:"-"`
 }
 
 func (r *oauthRequest) GetId() string {
@@ -14,6 +14,14 @@ type AuthorizeRequest interface {
 	HandledResponseType(responseType string)
 	// Returns true if the response type has been handled; false otherwise
 	IsResponseTypeHandled(responseType string) bool
+	// Get the PKCE code_challenge
+	GetCodeChallenge() string
+	// Set the PKCE code_challenge
+	SetCodeChallenge(challenge string)
+	// Get the PKCE code_challenge_method
+	GetCodeChallengeMethod() string
+	// Set the PKCE code_challenge_method
+	SetCodeChallengeMethod(method string)
 }
 
 func NewAuthorizeRequest() AuthorizeRequest {
@@ -21,15 +29,19 @@ func NewAuthorizeRequest() AuthorizeRequest {
 		oauthRequest: NewRequest().(*oauthRequest),
 		ResponseTypes: make([]string, 0),
 		State: "",
+		CodeChallenge: "",
+		CodeChallengeMethod: "",
 		handleMap: make(map[string]struct{}),
 	}
 }
 
 type authorizeRequest struct {
 	*oauthRequest
-	ResponseTypes []string				`json:"response_types"`
-	State         string				`json:"state"`
-	handleMap     map[string]struct{}	`json:"-"`
+	ResponseTypes       []string            `json:"response_types"`
+	State               string              `json:"state"`
+	CodeChallenge       string              `json:"code_challenge"`
+	CodeChallengeMethod string              `json:"code_challenge_method"`
+	handleMap           map[string]struct{} `json:"-"`
 }
 
 func (r *authorizeRequest) GetResponseTypes() []string {
@@ -57,4 +69,18 @@ func (r *authorizeRequest) IsResponseTypeHandled(responseType string) bool {
 	return ok
 }
 
+func (r *authorizeRequest) GetCodeChallenge() string {
+	return r.CodeChallenge
+}
+
+func (r *authorizeRequest) SetCodeChallenge(challenge string) {
+	r.CodeChallenge = challenge
+}
 
+func (r *authorizeRequest) GetCodeChallengeMethod() string {
+	return r.CodeChallengeMethod
+}
+
+func (r *authorizeRequest) SetCodeChallengeMethod(method string) {
+	r.CodeChallengeMethod = method
+}
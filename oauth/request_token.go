@@ -14,6 +14,18 @@ type TokenRequest interface {
 	GetRefreshToken() string
 	// set the refresh token
 	SetRefreshToken(token string)
+	// Get the supplied PKCE code_verifier
+	GetCodeVerifier() string
+	// set the PKCE code_verifier
+	SetCodeVerifier(verifier string)
+	// Get the supplied resource owner username
+	GetUsername() string
+	// set the resource owner username
+	SetUsername(username string)
+	// Get the supplied resource owner password
+	GetPassword() string
+	// set the resource owner password
+	SetPassword(password string)
 }
 
 func NewTokenRequest() TokenRequest {
@@ -22,14 +34,22 @@ func NewTokenRequest() TokenRequest {
 		GrantTypes: make([]string, 0),
 		Code: "",
 		RefreshToken: "",
+		CodeVerifier: "",
+		Username: "",
+		Password: "",
 	}
 }
 
 type oauthTokenRequest struct {
 	*oauthRequest
 	GrantTypes		[]string 	`json:"grant_types"`
-	Code 			string		`json:"code"`
-	RefreshToken	string		`json:"refresh_token"`
+	// code and refresh token are never persisted along with the request, repositories store their identifiers instead
+	Code 			string		`json:"-"`
+	RefreshToken	string		`json:"-"`
+	CodeVerifier	string		`json:"code_verifier"`
+	Username		string		`json:"username"`
+	// password is never persisted along with the request
+	Password		string		`json:"-"`
 }
 
 func (r *oauthTokenRequest) GetGrantTypes() []string {
@@ -56,4 +76,26 @@ func (r *oauthTokenRequest) SetRefreshToken(token string) {
 	r.RefreshToken = token
 }
 
+func (r *oauthTokenRequest) GetCodeVerifier() string {
+	return r.CodeVerifier
+}
+
+func (r *oauthTokenRequest) SetCodeVerifier(verifier string) {
+	r.CodeVerifier = verifier
+}
+
+func (r *oauthTokenRequest) GetUsername() string {
+	return r.Username
+}
 
+func (r *oauthTokenRequest) SetUsername(username string) {
+	r.Username = username
+}
+
+func (r *oauthTokenRequest) GetPassword() string {
+	return r.Password
+}
+
+func (r *oauthTokenRequest) SetPassword(password string) {
+	r.Password = password
+}
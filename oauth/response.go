@@ -7,6 +7,7 @@ const (
 	TokenType    = "token_type"
 	ExpiresIn    = "expires_in"
 	RefreshToken = "refresh_token"
+	State        = "state"
 )
 
 type Response map[string]interface{}
@@ -0,0 +1,52 @@
+package oauth
+
+import (
+	"bytes"
+	"github.com/imulab-z/platform-sdk/spi"
+	"html/template"
+	"net/http"
+	"net/url"
+)
+
+// Template for the OAuth 2.0 Form Post Response Mode. html/template takes care of escaping every value according to
+// its context, so that parameters containing markup cannot break out of the form.
+var formPostTemplate = template.Must(template.New("form_post").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Submit This Form</title></head>
+<body onload="javascript:document.forms[0].submit()">
+<form method="post" action="{{.Action}}">
+{{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{$v}}"/>
+{{end}}<noscript><button type="submit">Continue</button></noscript>
+</form>
+</body>
+</html>
+`))
+
+// Render an auto-submitting HTML form which posts the parameters to the redirect uri.
+func writeFormPost(w http.ResponseWriter, redirectUri string, params url.Values) error {
+	data := struct {
+		Action template.URL
+		Params map[string]string
+	}{
+		// redirect uri is trusted as it has been matched against client registration, this also allows custom
+		// schemes which html/template would otherwise reject.
+		Action: template.URL(redirectUri),
+		Params: make(map[string]string),
+	}
+	for k := range params {
+		data.Params[k] = params.Get(k)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := formPostTemplate.Execute(buf, data); err != nil {
+		return spi.ErrServerError(err)
+	}
+
+	w.Header().Set("Content-Type", "text/html;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
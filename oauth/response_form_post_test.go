@@ -0,0 +1,29 @@
+package oauth
+
+import (
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestWriteFormPost(t *testing.T) {
+	params := url.Values{}
+	params.Set(Code, "some-code")
+	params.Set(State, `"><script>alert('xss')</script>`)
+
+	w := httptest.NewRecorder()
+	err := WriteAuthorizeRedirect(w, `https://test.org/callback?a=1&b="2"`, spi.ResponseModeFormPost, params)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html;charset=UTF-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	body := w.Body.String()
+	assert.Contains(t, body, `<form method="post" action="https://test.org/callback?a=1&amp;b=%222%22">`)
+	assert.Contains(t, body, `<input type="hidden" name="code" value="some-code"/>`)
+	assert.Contains(t, body, `<input type="hidden" name="state" value="&#34;&gt;&lt;script&gt;alert(&#39;xss&#39;)&lt;/script&gt;"/>`)
+	assert.NotContains(t, body, "<script>")
+}
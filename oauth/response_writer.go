@@ -0,0 +1,174 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/imulab-z/platform-sdk/spi"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Returns the response_mode to be used when rendering the authorize response for the request. If the request carries
+// an explicitly requested response_mode (i.e. oidc.AuthorizeRequest), that value is respected. Otherwise, the default
+// mode for the requested response types is used: query for the 'code' response type alone, and fragment for anything
+// else, as tokens must not be delivered through the query component.
+func ResponseModeOf(req AuthorizeRequest) string {
+	if modeAware, ok := req.(interface{ GetResponseMode() string }); ok && len(modeAware.GetResponseMode()) > 0 {
+		return modeAware.GetResponseMode()
+	}
+	return DefaultResponseModeOf(req)
+}
+
+// Returns the default response_mode for the requested response types of the request, disregarding any explicitly
+// requested response_mode.
+func DefaultResponseModeOf(req AuthorizeRequest) string {
+	if V(req.GetResponseTypes()).ContainsExactly(spi.ResponseTypeCode) {
+		return spi.ResponseModeQuery
+	}
+	return spi.ResponseModeFragment
+}
+
+// Render the successful authorize response by redirecting the user agent back to the request's redirect_uri. The
+// state parameter, if supplied in the request, is always echoed back.
+func WriteAuthorizeResponse(w http.ResponseWriter, req AuthorizeRequest, resp Response) error {
+	return WriteAuthorizeRedirect(w, req.GetRedirectUri(), ResponseModeOf(req), AuthorizeResponseValues(req, resp))
+}
+
+// Render the authorize error. If the request has a validated redirect_uri, the error is delivered to the client by
+// redirecting the user agent back to the redirect_uri along with the state parameter. Otherwise, as the client cannot
+// be trusted to be the intended recipient yet, the error is rendered directly to the user agent.
+func WriteAuthorizeError(w http.ResponseWriter, req AuthorizeRequest, err error) error {
+	oauthErr := spi.AsOAuthError(err)
+
+	if req == nil || len(req.GetRedirectUri()) == 0 {
+		return writeJsonError(w, oauthErr)
+	}
+
+	return WriteAuthorizeRedirect(w, req.GetRedirectUri(), errorResponseModeOf(req), AuthorizeErrorValues(req, oauthErr))
+}
+
+// Returns the response_mode to deliver an error with. As the error may well be the rejection of the requested
+// response_mode, modes not supported by WriteAuthorizeRedirect fall back to the default mode of the request.
+func errorResponseModeOf(req AuthorizeRequest) string {
+	switch mode := ResponseModeOf(req); mode {
+	case spi.ResponseModeQuery, spi.ResponseModeFragment, spi.ResponseModeFormPost:
+		return mode
+	default:
+		return DefaultResponseModeOf(req)
+	}
+}
+
+// Returns the parameters of a successful authorize response, including the state parameter echoed from the request.
+func AuthorizeResponseValues(req AuthorizeRequest, resp Response) url.Values {
+	params := ResponseValues(resp)
+	if len(req.GetState()) > 0 {
+		params.Set(State, req.GetState())
+	}
+	return params
+}
+
+// Returns the parameters of an authorize error response, including the state parameter echoed from the request.
+func AuthorizeErrorValues(req AuthorizeRequest, err *spi.OAuthError) url.Values {
+	params := url.Values{}
+	params.Set("error", err.Err)
+	if len(err.Reason) > 0 {
+		params.Set("error_description", err.Reason)
+	}
+	if len(req.GetState()) > 0 {
+		params.Set(State, req.GetState())
+	}
+	return params
+}
+
+// Redirect the user agent to the redirect uri with the parameters encoded according to the response mode. For the
+// form_post response mode, an auto-submitting HTML form that posts the parameters to the redirect uri is rendered
+// instead.
+//
+// This method is exposed to reduce the work of rolling out custom response modes.
+func WriteAuthorizeRedirect(w http.ResponseWriter, redirectUri string, responseMode string, params url.Values) error {
+	if responseMode == spi.ResponseModeFormPost {
+		return writeFormPost(w, redirectUri, params)
+	}
+
+	u, err := url.Parse(redirectUri)
+	if err != nil {
+		return spi.ErrServerError(err)
+	}
+
+	var location string
+	switch responseMode {
+	case spi.ResponseModeQuery:
+		query := u.Query()
+		for k, v := range params {
+			query[k] = v
+		}
+		u.RawQuery = query.Encode()
+		location = u.String()
+	case spi.ResponseModeFragment:
+		// fragment is assembled manually to avoid url.URL escaping the already encoded parameters again.
+		u.Fragment = ""
+		location = u.String() + "#" + params.Encode()
+	default:
+		return spi.ErrServerErrorf("unsupported response_mode %s", responseMode)
+	}
+
+	w.Header().Set("Location", location)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusFound)
+	return nil
+}
+
+// Render the successful token response as JSON.
+func WriteTokenResponse(w http.ResponseWriter, resp Response) error {
+	return writeJson(w, http.StatusOK, resp)
+}
+
+// Render the token error as JSON, along with any headers (i.e. WWW-Authenticate) carried by the error.
+func WriteTokenError(w http.ResponseWriter, err error) error {
+	return writeJsonError(w, spi.AsOAuthError(err))
+}
+
+// Converts the response to url.Values so that it can be encoded as parameters.
+func ResponseValues(resp Response) url.Values {
+	values := url.Values{}
+	for k, v := range resp {
+		switch v.(type) {
+		case string:
+			values.Set(k, v.(string))
+		case []string:
+			values.Set(k, strings.Join(v.([]string), " "))
+		default:
+			values.Set(k, fmt.Sprint(v))
+		}
+	}
+	return values
+}
+
+func writeJsonError(w http.ResponseWriter, err *spi.OAuthError) error {
+	for k, v := range err.Headers {
+		w.Header().Set(k, v)
+	}
+
+	code := err.Code
+	if code == 0 {
+		code = http.StatusBadRequest
+	}
+
+	return writeJson(w, code, err)
+}
+
+func writeJson(w http.ResponseWriter, code int, body interface{}) error {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return spi.ErrServerError(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(code)
+
+	_, err = w.Write(raw)
+	return err
+}
@@ -0,0 +1,159 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestWriteAuthorizeResponse(t *testing.T) {
+	for _, v := range []struct {
+		name      string
+		reqFunc   func() AuthorizeRequest
+		resp      Response
+		assertion func(t *testing.T, location string)
+	}{
+		{
+			name: "code response in query",
+			reqFunc: func() AuthorizeRequest {
+				r := NewAuthorizeRequest()
+				r.AddResponseTypes(spi.ResponseTypeCode)
+				r.SetRedirectUri("https://test.org/callback?foo=bar")
+				r.SetState("12345678")
+				return r
+			},
+			resp: Response{Code: "some-code"},
+			assertion: func(t *testing.T, raw string) {
+				location, err := url.Parse(raw)
+				assert.Nil(t, err)
+				assert.Equal(t, "bar", location.Query().Get("foo"))
+				assert.Equal(t, "some-code", location.Query().Get(Code))
+				assert.Equal(t, "12345678", location.Query().Get(State))
+				assert.Empty(t, location.Fragment)
+			},
+		},
+		{
+			name: "token response in fragment",
+			reqFunc: func() AuthorizeRequest {
+				r := NewAuthorizeRequest()
+				r.AddResponseTypes(spi.ResponseTypeToken)
+				r.SetRedirectUri("https://test.org/callback")
+				r.SetState("a b&c")
+				return r
+			},
+			resp: Response{AccessToken: "some-token", TokenType: "Bearer", ExpiresIn: int64(3600)},
+			assertion: func(t *testing.T, raw string) {
+				parts := strings.SplitN(raw, "#", 2)
+				assert.Len(t, parts, 2)
+				assert.Equal(t, "https://test.org/callback", parts[0])
+				fragment, err := url.ParseQuery(parts[1])
+				assert.Nil(t, err)
+				assert.Equal(t, "some-token", fragment.Get(AccessToken))
+				assert.Equal(t, "3600", fragment.Get(ExpiresIn))
+				assert.Equal(t, "a b&c", fragment.Get(State))
+			},
+		},
+	} {
+		w := httptest.NewRecorder()
+		err := WriteAuthorizeResponse(w, v.reqFunc(), v.resp)
+		assert.Nil(t, err, v.name)
+		assert.Equal(t, http.StatusFound, w.Code, v.name)
+		v.assertion(t, w.Header().Get("Location"))
+	}
+}
+
+func TestWriteAuthorizeError(t *testing.T) {
+	t.Run("redirect error with state", func(t *testing.T) {
+		r := NewAuthorizeRequest()
+		r.AddResponseTypes(spi.ResponseTypeCode)
+		r.SetRedirectUri("https://test.org/callback")
+		r.SetState("12345678")
+
+		w := httptest.NewRecorder()
+		assert.Nil(t, WriteAuthorizeError(w, r, spi.ErrAccessDenied("user denied")))
+		assert.Equal(t, http.StatusFound, w.Code)
+
+		location, err := url.Parse(w.Header().Get("Location"))
+		assert.Nil(t, err)
+		assert.Equal(t, "access_denied", location.Query().Get("error"))
+		assert.Equal(t, "user denied", location.Query().Get("error_description"))
+		assert.Equal(t, "12345678", location.Query().Get(State))
+	})
+
+	t.Run("unsupported response_mode falls back to default", func(t *testing.T) {
+		r := &responseModeTestRequest{AuthorizeRequest: NewAuthorizeRequest(), mode: "bogus"}
+		r.AddResponseTypes(spi.ResponseTypeCode)
+		r.SetRedirectUri("https://test.org/callback")
+
+		w := httptest.NewRecorder()
+		assert.Nil(t, WriteAuthorizeError(w, r, spi.ErrInvalidRequest("response_mode is not supported.")))
+		assert.Equal(t, http.StatusFound, w.Code)
+
+		location, err := url.Parse(w.Header().Get("Location"))
+		assert.Nil(t, err)
+		assert.Equal(t, "invalid_request", location.Query().Get("error"))
+	})
+
+	t.Run("direct error without redirect_uri", func(t *testing.T) {
+		r := NewAuthorizeRequest()
+		r.SetState("12345678")
+
+		w := httptest.NewRecorder()
+		assert.Nil(t, WriteAuthorizeError(w, r, ErrNoRedirectUri))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, w.Header().Get("Location"))
+
+		body := make(map[string]string)
+		assert.Nil(t, json.NewDecoder(w.Body).Decode(&body))
+		assert.Equal(t, "invalid_request", body["error"])
+	})
+}
+
+func TestWriteTokenResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	assert.Nil(t, WriteTokenResponse(w, Response{AccessToken: "some-token", ExpiresIn: int64(3600)}))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "no-cache", w.Header().Get("Pragma"))
+
+	body := make(map[string]interface{})
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, "some-token", body[AccessToken])
+	assert.Equal(t, float64(3600), body[ExpiresIn])
+}
+
+func TestWriteTokenError(t *testing.T) {
+	t.Run("oauth error with headers", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		assert.Nil(t, WriteTokenError(w, spi.ErrInvalidClient("bad secret", "Basic")))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("generic error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		assert.Nil(t, WriteTokenError(w, errors.New("boom")))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+		body := make(map[string]string)
+		assert.Nil(t, json.NewDecoder(w.Body).Decode(&body))
+		assert.Equal(t, "server_error", body["error"])
+	})
+}
+
+// support: AuthorizeRequest with an explicitly requested response_mode
+type responseModeTestRequest struct {
+	AuthorizeRequest
+	mode string
+}
+
+func (r *responseModeTestRequest) GetResponseMode() string {
+	return r.mode
+}
@@ -6,7 +6,9 @@ type Session interface {
 	GetSubject() string
 	// Sets a new user subject
 	SetSubject(subject string)
-	// Returns the request id associated with the last request
+	// Returns the request id associated with the last request. For tokens issued at the token endpoint, this is the id of
+	// the request they were derived from (i.e. the authorize request, or the parent refresh request), and is persisted
+	// with the tokens.
 	GetLastRequestId() string
 	// Sets the request id for the last request (used in updating session)
 	SetLastRequestId(id string)
@@ -37,7 +39,7 @@ type oauthSession struct {
 	Subject 	string					`json:"subject"`
 	Scopes		[]string				`json:"granted_scopes"`
 	Claims 		map[string]interface{}	`json:"claims"`
-	LastReqId	string					`json:"-"`
+	LastReqId	string					`json:"last_request_id"`
 }
 
 func (s *oauthSession) GetLastRequestId() string {
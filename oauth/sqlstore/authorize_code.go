@@ -0,0 +1,104 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"time"
+)
+
+var (
+	_ oauth.ConsumableAuthorizeCodeRepository = (*AuthorizeCodeRepository)(nil)
+)
+
+// Default time to live of authorization codes.
+const DefaultAuthorizeCodeTTL = 10 * time.Minute
+
+// oauth.AuthorizeCodeRepository backed by the oauth_authorize_code table, which supports replay detection. Codes are
+// stored under the identifier computed by the Strategy, and expire after TTL, consumed or not.
+type AuthorizeCodeRepository struct {
+	Strategy oauth.AuthorizeCodeStrategy
+	TTL      time.Duration
+	table    *requestTable
+}
+
+// Create a new AuthorizeCodeRepository whose codes expire after ttl. A zero ttl defaults to DefaultAuthorizeCodeTTL.
+func NewAuthorizeCodeRepository(store *Store, strategy oauth.AuthorizeCodeStrategy, ttl time.Duration) *AuthorizeCodeRepository {
+	if ttl == 0 {
+		ttl = DefaultAuthorizeCodeTTL
+	}
+	return &AuthorizeCodeRepository{
+		Strategy: strategy,
+		TTL:      ttl,
+		table:    &requestTable{store: store, name: tableAuthorizeCode},
+	}
+}
+
+func (r *AuthorizeCodeRepository) GetRequest(ctx context.Context, code string) (oauth.AuthorizeRequest, error) {
+	id, err := r.Strategy.ComputeIdentifier(code)
+	if err != nil {
+		return nil, spi.ErrInvalidGrant("authorization code is malformed.")
+	}
+
+	req, err := r.table.get(ctx, id)
+	if err != nil {
+		return nil, spi.AsOAuthError(err)
+	}
+
+	authReq, ok := req.(oauth.AuthorizeRequest)
+	if !ok {
+		return nil, spi.ErrInvalidGrant("authorization code is unknown or expired.")
+	}
+
+	return authReq, nil
+}
+
+func (r *AuthorizeCodeRepository) Save(ctx context.Context, code string, req oauth.AuthorizeRequest) error {
+	id, err := r.Strategy.ComputeIdentifier(code)
+	if err != nil {
+		return spi.AsOAuthError(err)
+	}
+	return r.table.save(ctx, id, req, r.TTL)
+}
+
+func (r *AuthorizeCodeRepository) Delete(ctx context.Context, code string) error {
+	id, err := r.Strategy.ComputeIdentifier(code)
+	if err != nil {
+		return spi.AsOAuthError(err)
+	}
+	return r.table.delete(ctx, id)
+}
+
+func (r *AuthorizeCodeRepository) MarkConsumed(ctx context.Context, code string, requestId string) (string, error) {
+	id, err := r.Strategy.ComputeIdentifier(code)
+	if err != nil {
+		return "", spi.ErrInvalidGrant("authorization code is malformed.")
+	}
+
+	result, err := r.table.store.exec(ctx,
+		"UPDATE "+tableAuthorizeCode+" SET consumed_by = ? WHERE id = ? AND consumed_by IS NULL AND expires_at > ?",
+		requestId, id, time.Now().Unix())
+	if err != nil {
+		return "", spi.AsOAuthError(err)
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return "", spi.AsOAuthError(err)
+	} else if n == 1 {
+		return "", nil
+	}
+
+	var previous sql.NullString
+	err = r.table.store.queryRow(ctx,
+		"SELECT consumed_by FROM "+tableAuthorizeCode+" WHERE id = ? AND expires_at > ?",
+		id, time.Now().Unix()).Scan(&previous)
+	switch {
+	case err == sql.ErrNoRows || (err == nil && !previous.Valid):
+		return "", spi.ErrInvalidGrant("authorization code is unknown or expired.")
+	case err != nil:
+		return "", spi.AsOAuthError(err)
+	}
+
+	return previous.String, nil
+}
@@ -0,0 +1,60 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/imulab-z/platform-sdk/spi"
+)
+
+var (
+	_ spi.ClientLookup = (*ClientLookup)(nil)
+)
+
+// spi.ClientLookup backed by the oauth_client table. Clients are stored as JSON, and restored with the Decode function,
+// since spi.OAuthClient is implemented by the user.
+type ClientLookup struct {
+	Store  *Store
+	Decode func(data []byte) (spi.OAuthClient, error)
+}
+
+// Save the client, replacing any client saved with the same id.
+func (l *ClientLookup) Save(ctx context.Context, client spi.OAuthClient) error {
+	data, err := json.Marshal(client)
+	if err != nil {
+		return err
+	}
+
+	result, err := l.Store.exec(ctx, "UPDATE "+tableClient+" SET data = ? WHERE id = ?", string(data), client.GetId())
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n > 0 {
+		return nil
+	}
+
+	_, err = l.Store.exec(ctx, "INSERT INTO "+tableClient+" (id, data) VALUES (?, ?)", client.GetId(), string(data))
+	return err
+}
+
+// Delete the client with the id, if saved.
+func (l *ClientLookup) Delete(ctx context.Context, id string) error {
+	_, err := l.Store.exec(ctx, "DELETE FROM "+tableClient+" WHERE id = ?", id)
+	return err
+}
+
+func (l *ClientLookup) FindById(ctx context.Context, id string) (spi.OAuthClient, error) {
+	var data string
+
+	err := l.Store.queryRow(ctx, "SELECT data FROM "+tableClient+" WHERE id = ?", id).Scan(&data)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, spi.ErrInvalidClient("client is not registered.", "")
+	case err != nil:
+		return nil, spi.AsOAuthError(err)
+	}
+
+	return l.Decode([]byte(data))
+}
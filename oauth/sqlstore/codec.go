@@ -0,0 +1,41 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+	"github.com/imulab-z/platform-sdk/oauth"
+)
+
+// Serializes requests for storage and back. The client is never serialized: its id is stored alongside the request
+// instead, and the client is restored upon decoding.
+type Codec interface {
+	// Serialize the request. Returns the kind of the request, which is stored alongside and supplied to Decode.
+	Encode(req oauth.Request) (kind string, data []byte, err error)
+	// Deserialize the request of the kind, and restore the client with the id.
+	Decode(ctx context.Context, kind string, clientId string, data []byte) (oauth.Request, error)
+}
+
+// Codec which serializes requests as JSON envelopes with the oauth.RequestCodec. The kind of a request is its type
+// discriminator. Open ID Connect deployments should register the oidc types with oidc.RegisterCodecTypes.
+type JsonCodec struct {
+	Requests *oauth.RequestCodec
+}
+
+func (c *JsonCodec) Encode(req oauth.Request) (string, []byte, error) {
+	kind, ok := c.Requests.TypeOf(req)
+	if !ok {
+		return "", nil, fmt.Errorf("request type %T is not registered", req)
+	}
+
+	data, err := c.Requests.Encode(req)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return kind, data, nil
+}
+
+// The envelope carries both the kind and the client id, hence they are not used.
+func (c *JsonCodec) Decode(ctx context.Context, kind string, clientId string, data []byte) (oauth.Request, error) {
+	return c.Requests.Decode(ctx, data)
+}
@@ -0,0 +1,229 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/imulab-z/platform-sdk/memstore"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/imulab-z/platform-sdk/test"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+func TestTokenEndpointWithStore(t *testing.T) {
+	s := new(TokenEndpointWithStoreTestSuite)
+	suite.Run(t, s)
+}
+
+// Runs the token endpoint with the Store as its transaction manager, so that operations which must outlive a rolled
+// back transaction are exercised against a real database.
+type TokenEndpointWithStoreTestSuite struct {
+	suite.Suite
+	db          *sql.DB
+	client      *endpointTestClient
+	codeRepo    *AuthorizeCodeRepository
+	accessRepo  *AccessTokenRepository
+	refreshRepo *RefreshTokenRepository
+	e           *oauth.TokenEndpoint
+}
+
+func (s *TokenEndpointWithStoreTestSuite) SetupTest() {
+	db, err := sql.Open("sqlite3", "file:"+uuid.NewV4().String()+"?mode=memory&cache=shared")
+	s.Require().Nil(err)
+	s.db = db
+
+	s.client = &endpointTestClient{MockClient: new(test.MockClient)}
+	store := NewStore(db, DialectSqlite, &JsonCodec{Requests: oauth.NewRequestCodec(memstore.NewClientLookup(s.client))})
+	s.Require().Nil(store.Migrate(context.Background()))
+
+	kid := "2C6E4A1B-9D3F-4E70-8B5A-F1C2D3E4A5B6"
+	s.codeRepo = NewAuthorizeCodeRepository(store,
+		oauth.NewHmacShaAuthorizeCodeStrategy(32, oauth.MustHmacSha256Strategy()), 0)
+	s.accessRepo = NewAccessTokenRepository(store,
+		oauth.NewRs256JwtAccessTokenStrategy("test", time.Hour, oauth.MustNewJwksWithRsaKeyForSigning(kid), kid), 0)
+	s.refreshRepo = NewRefreshTokenRepository(store,
+		oauth.NewHmacShaRefreshTokenStrategy(32, oauth.MustHmacSha256Strategy()), 0)
+
+	accessHelper := &oauth.AccessTokenHelper{
+		Strategy: s.accessRepo.Strategy,
+		Repo:     s.accessRepo,
+		Lifespan: time.Hour,
+		Mode:     oauth.PersistSync,
+	}
+	refreshHelper := &oauth.RefreshTokenHelper{
+		Strategy: s.refreshRepo.Strategy,
+		Repo:     s.refreshRepo,
+		Mode:     oauth.PersistSync,
+	}
+	rotationRepo := memstore.NewRefreshTokenRotationRepository(0)
+
+	s.e = &oauth.TokenEndpoint{
+		Handlers: []oauth.TokenHandler{
+			&oauth.AuthorizeCodeHandler{
+				CodeRepo:           s.codeRepo,
+				CodeStrategy:       s.codeRepo.Strategy,
+				AccessTokenHelper:  accessHelper,
+				RefreshTokenHelper: refreshHelper,
+				RotationRepo:       rotationRepo,
+			},
+			&oauth.RefreshHandler{
+				AccessTokenHelper:    accessHelper,
+				RefreshTokenHelper:   refreshHelper,
+				AccessTokenRepo:      s.accessRepo,
+				RefreshTokenRepo:     s.refreshRepo,
+				RefreshTokenStrategy: s.refreshRepo.Strategy,
+				RotationRepo:         rotationRepo,
+			},
+		},
+		Transactions: store,
+	}
+}
+
+func (s *TokenEndpointWithStoreTestSuite) TearDownTest() {
+	s.Assert().Nil(s.db.Close())
+}
+
+func (s *TokenEndpointWithStoreTestSuite) TestCommit() {
+	resp, err := s.e.IssueToken(context.Background(), s.codeRequest(s.newCode()))
+	s.Require().Nil(err)
+
+	_, err = s.accessRepo.GetRequest(context.Background(), resp.GetString(oauth.AccessToken))
+	s.Assert().Nil(err)
+	_, err = s.refreshRepo.GetRequest(context.Background(), resp.GetString(oauth.RefreshToken))
+	s.Assert().Nil(err)
+}
+
+func (s *TokenEndpointWithStoreTestSuite) TestRollback() {
+	code := s.newCode()
+	handlers := s.e.Handlers
+	s.e.Handlers = append(handlers, new(failingTokenHandler))
+
+	_, err := s.e.IssueToken(context.Background(), s.codeRequest(code))
+	s.Require().NotNil(err)
+	s.Assert().Equal("server_error", err.(*spi.OAuthError).Err)
+
+	for _, table := range []string{tableAccessToken, tableRefreshToken} {
+		var count int
+		s.Require().Nil(s.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count))
+		s.Assert().Equal(0, count, table)
+	}
+
+	// the redemption was rolled back along with the tokens, so the code is still usable
+	s.e.Handlers = handlers
+	_, err = s.e.IssueToken(context.Background(), s.codeRequest(code))
+	s.Assert().Nil(err)
+}
+
+func (s *TokenEndpointWithStoreTestSuite) TestCodeReplayRevokesTokens() {
+	code := s.newCode()
+
+	resp, err := s.e.IssueToken(context.Background(), s.codeRequest(code))
+	s.Require().Nil(err)
+
+	_, err = s.e.IssueToken(context.Background(), s.codeRequest(code))
+	s.Require().NotNil(err)
+	s.Assert().Equal("invalid_grant", err.(*spi.OAuthError).Err)
+
+	_, err = s.accessRepo.GetRequest(context.Background(), resp.GetString(oauth.AccessToken))
+	s.Assert().NotNil(err)
+	_, err = s.refreshRepo.GetRequest(context.Background(), resp.GetString(oauth.RefreshToken))
+	s.Assert().NotNil(err)
+}
+
+func (s *TokenEndpointWithStoreTestSuite) TestCodeReplayRevokesFamily() {
+	code := s.newCode()
+
+	resp, err := s.e.IssueToken(context.Background(), s.codeRequest(code))
+	s.Require().Nil(err)
+
+	resp, err = s.e.IssueToken(context.Background(), s.refreshRequest(resp.GetString(oauth.RefreshToken)))
+	s.Require().Nil(err)
+
+	_, err = s.e.IssueToken(context.Background(), s.codeRequest(code))
+	s.Require().NotNil(err)
+	s.Assert().Equal("invalid_grant", err.(*spi.OAuthError).Err)
+
+	_, err = s.accessRepo.GetRequest(context.Background(), resp.GetString(oauth.AccessToken))
+	s.Assert().NotNil(err)
+	_, err = s.refreshRepo.GetRequest(context.Background(), resp.GetString(oauth.RefreshToken))
+	s.Assert().NotNil(err)
+}
+
+func (s *TokenEndpointWithStoreTestSuite) TestRefreshReplayRevokesFamily() {
+	resp, err := s.e.IssueToken(context.Background(), s.codeRequest(s.newCode()))
+	s.Require().Nil(err)
+	parent := resp.GetString(oauth.RefreshToken)
+
+	resp, err = s.e.IssueToken(context.Background(), s.refreshRequest(parent))
+	s.Require().Nil(err)
+	child := resp.GetString(oauth.RefreshToken)
+
+	_, err = s.e.IssueToken(context.Background(), s.refreshRequest(parent))
+	s.Require().NotNil(err)
+	s.Assert().Equal("invalid_grant", err.(*spi.OAuthError).Err)
+
+	_, err = s.refreshRepo.GetRequest(context.Background(), child)
+	s.Assert().NotNil(err)
+	_, err = s.accessRepo.GetRequest(context.Background(), resp.GetString(oauth.AccessToken))
+	s.Assert().NotNil(err)
+}
+
+func (s *TokenEndpointWithStoreTestSuite) newCode() string {
+	req := oauth.NewAuthorizeRequest()
+	req.SetClient(s.client)
+	req.AddResponseTypes(spi.ResponseTypeCode)
+	req.SetRedirectUri(s.client.GetRedirectUris()[0])
+	req.GetSession().SetSubject("test user")
+	req.GetSession().AddGrantedScopes("foo", spi.ScopeOfflineAccess)
+
+	code, err := s.codeRepo.Strategy.NewCode(context.Background(), req)
+	s.Require().Nil(err)
+	s.Require().Nil(s.codeRepo.Save(context.Background(), code, req))
+	return code
+}
+
+func (s *TokenEndpointWithStoreTestSuite) codeRequest(code string) oauth.TokenRequest {
+	req := oauth.NewTokenRequest()
+	req.SetClient(s.client)
+	req.AddGrantTypes(spi.GrantTypeCode)
+	req.SetCode(code)
+	req.SetRedirectUri(s.client.GetRedirectUris()[0])
+	return req
+}
+
+func (s *TokenEndpointWithStoreTestSuite) refreshRequest(token string) oauth.TokenRequest {
+	req := oauth.NewTokenRequest()
+	req.SetClient(s.client)
+	req.AddGrantTypes(spi.GrantTypeRefresh)
+	req.SetRefreshToken(token)
+	return req
+}
+
+// support: OAuthClient capable of the refresh_token grant
+type endpointTestClient struct {
+	*test.MockClient
+}
+
+func (c *endpointTestClient) GetGrantTypes() []string {
+	return []string{spi.GrantTypeCode, spi.GrantTypeRefresh}
+}
+
+// support: TokenHandler failing after the other handlers issued their tokens
+type failingTokenHandler struct{}
+
+func (h *failingTokenHandler) UpdateSession(ctx context.Context, req oauth.TokenRequest) error {
+	return nil
+}
+
+func (h *failingTokenHandler) IssueToken(ctx context.Context, req oauth.TokenRequest, resp oauth.Response) error {
+	return spi.ErrServerError(errors.New("connection lost"))
+}
+
+func (h *failingTokenHandler) SupportsTokenRequest(req oauth.TokenRequest) bool {
+	return true
+}
@@ -0,0 +1,117 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	tableMigration     = "oauth_schema_migration"
+	tableAuthorizeCode = "oauth_authorize_code"
+	tableAccessToken   = "oauth_access_token"
+	tableRefreshToken  = "oauth_refresh_token"
+	tableClient        = "oauth_client"
+)
+
+// Schema migrations, applied in order. Released migrations must never be changed, new ones are appended.
+var migrations = []string{
+	// 1: codes, tokens and clients
+	`CREATE TABLE ` + tableAuthorizeCode + ` (
+		id           VARCHAR(255) NOT NULL PRIMARY KEY,
+		request_id   VARCHAR(255) NOT NULL,
+		client_id    VARCHAR(255) NOT NULL,
+		request_kind VARCHAR(32)  NOT NULL,
+		data         TEXT         NOT NULL,
+		consumed_by  VARCHAR(255),
+		expires_at   BIGINT       NOT NULL
+	)`,
+	`CREATE INDEX idx_` + tableAuthorizeCode + `_expires_at ON ` + tableAuthorizeCode + ` (expires_at)`,
+	`CREATE TABLE ` + tableAccessToken + ` (
+		id           VARCHAR(255) NOT NULL PRIMARY KEY,
+		request_id   VARCHAR(255) NOT NULL,
+		client_id    VARCHAR(255) NOT NULL,
+		request_kind VARCHAR(32)  NOT NULL,
+		data         TEXT         NOT NULL,
+		expires_at   BIGINT       NOT NULL
+	)`,
+	`CREATE INDEX idx_` + tableAccessToken + `_request_id ON ` + tableAccessToken + ` (request_id)`,
+	`CREATE INDEX idx_` + tableAccessToken + `_expires_at ON ` + tableAccessToken + ` (expires_at)`,
+	`CREATE TABLE ` + tableRefreshToken + ` (
+		id           VARCHAR(255) NOT NULL PRIMARY KEY,
+		request_id   VARCHAR(255) NOT NULL,
+		client_id    VARCHAR(255) NOT NULL,
+		request_kind VARCHAR(32)  NOT NULL,
+		data         TEXT         NOT NULL,
+		expires_at   BIGINT       NOT NULL
+	)`,
+	`CREATE INDEX idx_` + tableRefreshToken + `_request_id ON ` + tableRefreshToken + ` (request_id)`,
+	`CREATE INDEX idx_` + tableRefreshToken + `_expires_at ON ` + tableRefreshToken + ` (expires_at)`,
+	`CREATE TABLE ` + tableClient + ` (
+		id   VARCHAR(255) NOT NULL PRIMARY KEY,
+		data TEXT         NOT NULL
+	)`,
+}
+
+// Apply the migrations which have not yet been applied. Applied migrations are tracked by version in the
+// oauth_schema_migration table. The pending versions are read, claimed and applied within a single transaction, so that
+// of processes migrating concurrently only one applies them; the others fail to claim a version and, once the winner
+// committed, find the schema up to date.
+func (s *Store) Migrate(ctx context.Context) error {
+	if _, err := s.DB.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+tableMigration+` (version INTEGER NOT NULL PRIMARY KEY)`); err != nil {
+		return err
+	}
+
+	err := s.migrate(ctx)
+	if err == nil {
+		return nil
+	}
+
+	// lost the race to a concurrent migration
+	if current, verr := s.currentVersion(ctx, s.DB); verr == nil && current >= len(migrations) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *Store) migrate(ctx context.Context) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// write before reading, so that databases locking on write serialize concurrent migrations here rather than
+	// failing them once they both read the version
+	if _, err := tx.ExecContext(ctx, `UPDATE `+tableMigration+` SET version = version WHERE version < 0`); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	current, err := s.currentVersion(ctx, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	for i := current; i < len(migrations); i++ {
+		// claim the version before applying it, a concurrent claim conflicts on the primary key
+		if _, err := tx.ExecContext(ctx, s.rebind(`INSERT INTO `+tableMigration+` (version) VALUES (?)`), i+1); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d failed: %s", i+1, err.Error())
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Returns the latest applied migration version, or 0 if none was applied.
+func (s *Store) currentVersion(ctx context.Context, exec executor) (int, error) {
+	var current int
+	err := exec.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM `+tableMigration).Scan(&current)
+	return current, err
+}
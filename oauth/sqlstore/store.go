@@ -0,0 +1,153 @@
+// Package sqlstore provides repositories backed by database/sql. Tokens and codes are stored under the identifier
+// computed by their strategy rather than the raw value, alongside the id of the request they were issued to and their
+// expiry. Requests are serialized with a Codec.
+//
+// The schema is created by Migrate. Expired rows are removed by Purge, or periodically by StartPurge.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	_ spi.TransactionManager = (*Store)(nil)
+	_ spi.Transaction        = (*transaction)(nil)
+)
+
+// SQL dialect of the database, which determines the bind parameter syntax.
+type Dialect int
+
+const (
+	// SQLite and MySQL style '?' bind parameters.
+	DialectSqlite Dialect = iota
+	DialectMysql
+	// PostgreSQL style '$n' bind parameters.
+	DialectPostgres
+)
+
+// Database handle shared by all repositories of this package. Store is also a spi.TransactionManager: repositories
+// enlist in the transaction carried by the context, so that endpoints configured with the Store perform all their
+// persistence operations in a single database transaction.
+type Store struct {
+	DB      *sql.DB
+	Dialect Dialect
+	Codec   Codec
+}
+
+// Create a new Store.
+func NewStore(db *sql.DB, dialect Dialect, codec Codec) *Store {
+	return &Store{DB: db, Dialect: dialect, Codec: codec}
+}
+
+// spi.Transaction backed by a database transaction.
+type transaction struct {
+	tx *sql.Tx
+}
+
+func (t *transaction) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *transaction) Rollback() error {
+	return t.tx.Rollback()
+}
+
+func (s *Store) Begin(ctx context.Context) (context.Context, spi.Transaction, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return ctx, nil, err
+	}
+	t := &transaction{tx: tx}
+	return spi.WithTransaction(ctx, t), t, nil
+}
+
+// Common interface of *sql.DB and *sql.Tx.
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Returns the database transaction carried by the context, if it was started by a Store; otherwise the database.
+func (s *Store) executor(ctx context.Context) executor {
+	if tx, ok := spi.TransactionFrom(ctx); ok {
+		if t, ok := tx.(*transaction); ok {
+			return t.tx
+		}
+	}
+	return s.DB
+}
+
+func (s *Store) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.executor(ctx).ExecContext(ctx, s.rebind(query), args...)
+}
+
+func (s *Store) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.executor(ctx).QueryRowContext(ctx, s.rebind(query), args...)
+}
+
+// Rewrite '?' bind parameters for the dialect.
+func (s *Store) rebind(query string) string {
+	if s.Dialect != DialectPostgres {
+		return query
+	}
+
+	var (
+		sb strings.Builder
+		n  = 0
+	)
+	for _, c := range query {
+		if c == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+		} else {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+// Tables with an expires_at column.
+var expiringTables = []string{tableAuthorizeCode, tableAccessToken, tableRefreshToken}
+
+// Delete rows which expired by the given time. Returns the number of rows deleted.
+func (s *Store) Purge(ctx context.Context, now time.Time) (int64, error) {
+	var total int64
+	for _, table := range expiringTables {
+		result, err := s.exec(ctx, "DELETE FROM "+table+" WHERE expires_at <= ?", now.Unix())
+		if err != nil {
+			return total, err
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			total += n
+		}
+	}
+	return total, nil
+}
+
+// Purge expired rows every interval until the context is cancelled. Failed purges are logged and retried on the next
+// tick.
+func (s *Store) StartPurge(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if n, err := s.Purge(ctx, now); err != nil {
+					logrus.WithError(err).Errorln("failed to purge expired rows.")
+				} else if n > 0 {
+					logrus.WithField("rows", n).Debugln("purged expired rows.")
+				}
+			}
+		}
+	}()
+}
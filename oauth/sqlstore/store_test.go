@@ -0,0 +1,278 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/imulab-z/platform-sdk/memstore"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/imulab-z/platform-sdk/test"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	s := new(StoreTestSuite)
+	suite.Run(t, s)
+}
+
+type StoreTestSuite struct {
+	suite.Suite
+	db          *sql.DB
+	store       *Store
+	client      *test.MockClient
+	codeRepo    *AuthorizeCodeRepository
+	accessRepo  *AccessTokenRepository
+	refreshRepo *RefreshTokenRepository
+}
+
+func (s *StoreTestSuite) SetupTest() {
+	db, err := sql.Open("sqlite3", "file:"+uuid.NewV4().String()+"?mode=memory&cache=shared")
+	s.Require().Nil(err)
+	s.db = db
+
+	s.client = new(test.MockClient)
+	s.store = NewStore(db, DialectSqlite, &JsonCodec{Requests: oauth.NewRequestCodec(memstore.NewClientLookup(s.client))})
+	s.Require().Nil(s.store.Migrate(context.Background()))
+
+	kid := "7A4F0E52-3C1D-4B8E-A6F2-91D3C5B7E804"
+	s.codeRepo = NewAuthorizeCodeRepository(s.store,
+		oauth.NewHmacShaAuthorizeCodeStrategy(32, oauth.MustHmacSha256Strategy()), 0)
+	s.accessRepo = NewAccessTokenRepository(s.store,
+		oauth.NewRs256JwtAccessTokenStrategy("test", time.Hour, oauth.MustNewJwksWithRsaKeyForSigning(kid), kid), 0)
+	s.refreshRepo = NewRefreshTokenRepository(s.store,
+		oauth.NewHmacShaRefreshTokenStrategy(32, oauth.MustHmacSha256Strategy()), 0)
+}
+
+func (s *StoreTestSuite) TearDownTest() {
+	s.Assert().Nil(s.db.Close())
+}
+
+func (s *StoreTestSuite) TestMigrateIsIdempotent() {
+	s.Assert().Nil(s.store.Migrate(context.Background()))
+
+	var version int
+	s.Require().Nil(s.db.QueryRow("SELECT MAX(version) FROM " + tableMigration).Scan(&version))
+	s.Assert().Equal(len(migrations), version)
+}
+
+func (s *StoreTestSuite) TestConcurrentMigrate() {
+	dir, err := ioutil.TempDir("", "sqlstore")
+	s.Require().Nil(err)
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(dir, "migrate.db")+"?_busy_timeout=5000")
+	s.Require().Nil(err)
+	defer db.Close()
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Assert().Nil(NewStore(db, DialectSqlite, s.store.Codec).Migrate(context.Background()))
+		}()
+	}
+	wg.Wait()
+
+	var count int
+	s.Require().Nil(db.QueryRow("SELECT COUNT(*) FROM " + tableMigration).Scan(&count))
+	s.Assert().Equal(len(migrations), count)
+}
+
+func (s *StoreTestSuite) TestAccessToken() {
+	ctx := context.Background()
+	req := s.newTokenRequest()
+	token, err := s.accessRepo.Strategy.NewToken(ctx, req)
+	s.Require().Nil(err)
+
+	s.Require().Nil(s.accessRepo.Save(ctx, token, req))
+
+	found, err := s.accessRepo.GetRequest(ctx, token)
+	s.Require().Nil(err)
+	s.Assert().Equal(req.GetId(), found.GetId())
+	s.Assert().Equal(s.client.GetId(), found.GetClient().GetId())
+	s.Assert().Equal("test user", found.GetSession().GetSubject())
+	s.Assert().Equal([]string{"foo"}, found.GetSession().GetGrantedScopes())
+	s.Assert().Equal([]string{spi.GrantTypeCode}, found.(oauth.TokenRequest).GetGrantTypes())
+
+	// the raw token is never stored
+	var count int
+	s.Require().Nil(s.db.QueryRow("SELECT COUNT(*) FROM "+tableAccessToken+" WHERE id = ?", token).Scan(&count))
+	s.Assert().Equal(0, count)
+
+	s.Require().Nil(s.accessRepo.Delete(ctx, token))
+	_, err = s.accessRepo.GetRequest(ctx, token)
+	s.Assert().NotNil(err)
+}
+
+func (s *StoreTestSuite) TestDeleteByRequestId() {
+	ctx := context.Background()
+	req1, req2 := s.newTokenRequest(), s.newTokenRequest()
+
+	tokens := make([]string, 0)
+	for _, req := range []oauth.Request{req1, req1, req2} {
+		token, err := s.refreshRepo.Strategy.NewToken(ctx, req)
+		s.Require().Nil(err)
+		s.Require().Nil(s.refreshRepo.Save(ctx, token, req))
+		tokens = append(tokens, token)
+	}
+
+	s.Require().Nil(s.refreshRepo.DeleteByRequestId(ctx, req1.GetId()))
+
+	for i, exists := range []bool{false, false, true} {
+		_, err := s.refreshRepo.GetRequest(ctx, tokens[i])
+		s.Assert().Equal(exists, err == nil, tokens[i])
+	}
+}
+
+func (s *StoreTestSuite) TestRawTokensNotPersisted() {
+	ctx := context.Background()
+
+	authReq := oauth.NewAuthorizeRequest()
+	authReq.SetClient(s.client)
+	code, err := s.codeRepo.Strategy.NewCode(ctx, authReq)
+	s.Require().Nil(err)
+	s.Require().Nil(s.codeRepo.Save(ctx, code, authReq))
+
+	req := s.newTokenRequest()
+	req.SetCode(code)
+	req.SetRefreshToken("parent-refresh-token")
+	token, err := s.refreshRepo.Strategy.NewToken(ctx, req)
+	s.Require().Nil(err)
+	s.Require().Nil(s.refreshRepo.Save(ctx, token, req))
+
+	for _, table := range []string{tableAuthorizeCode, tableRefreshToken} {
+		rows, err := s.db.Query("SELECT id, request_id, client_id, request_kind, data FROM " + table)
+		s.Require().Nil(err)
+		for rows.Next() {
+			columns := make([]string, 5)
+			s.Require().Nil(rows.Scan(&columns[0], &columns[1], &columns[2], &columns[3], &columns[4]))
+			for _, column := range columns {
+				for _, raw := range []string{code, token, "parent-refresh-token"} {
+					s.Assert().NotContains(column, raw, table)
+				}
+			}
+		}
+		s.Require().Nil(rows.Close())
+	}
+}
+
+func (s *StoreTestSuite) TestAuthorizeCode() {
+	ctx := context.Background()
+	req := oauth.NewAuthorizeRequest()
+	req.SetClient(s.client)
+	req.AddResponseTypes(spi.ResponseTypeCode)
+	req.SetRedirectUri(s.client.GetRedirectUris()[0])
+	req.SetCodeChallenge("E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM")
+
+	code, err := s.codeRepo.Strategy.NewCode(ctx, req)
+	s.Require().Nil(err)
+	s.Require().Nil(s.codeRepo.Save(ctx, code, req))
+
+	found, err := s.codeRepo.GetRequest(ctx, code)
+	s.Require().Nil(err)
+	s.Assert().Equal(req.GetRedirectUri(), found.GetRedirectUri())
+	s.Assert().Equal(req.GetCodeChallenge(), found.GetCodeChallenge())
+	s.Assert().Equal([]string{spi.ResponseTypeCode}, found.GetResponseTypes())
+
+	previous, err := s.codeRepo.MarkConsumed(ctx, code, "first")
+	s.Assert().Nil(err)
+	s.Assert().Empty(previous)
+
+	previous, err = s.codeRepo.MarkConsumed(ctx, code, "second")
+	s.Assert().Nil(err)
+	s.Assert().Equal("first", previous)
+
+	s.Require().Nil(s.codeRepo.Delete(ctx, code))
+	_, err = s.codeRepo.MarkConsumed(ctx, code, "third")
+	s.Assert().NotNil(err)
+}
+
+func (s *StoreTestSuite) TestTransaction() {
+	req := s.newTokenRequest()
+	token, err := s.refreshRepo.Strategy.NewToken(context.Background(), req)
+	s.Require().Nil(err)
+
+	ctx, tx, err := s.store.Begin(context.Background())
+	s.Require().Nil(err)
+	s.Require().Nil(s.refreshRepo.Save(ctx, token, req))
+	_, err = s.refreshRepo.GetRequest(ctx, token)
+	s.Assert().Nil(err)
+	s.Require().Nil(tx.Rollback())
+
+	_, err = s.refreshRepo.GetRequest(context.Background(), token)
+	s.Assert().NotNil(err)
+}
+
+func (s *StoreTestSuite) TestPurge() {
+	ctx := context.Background()
+	s.refreshRepo.TTL = -time.Second
+
+	req := s.newTokenRequest()
+	token, err := s.refreshRepo.Strategy.NewToken(ctx, req)
+	s.Require().Nil(err)
+	s.Require().Nil(s.refreshRepo.Save(ctx, token, req))
+
+	// expired rows are invisible even before being purged
+	_, err = s.refreshRepo.GetRequest(ctx, token)
+	s.Assert().NotNil(err)
+
+	n, err := s.store.Purge(ctx, time.Now())
+	s.Assert().Nil(err)
+	s.Assert().Equal(int64(1), n)
+}
+
+func (s *StoreTestSuite) TestClientLookup() {
+	ctx := context.Background()
+	lookup := &ClientLookup{
+		Store: s.store,
+		Decode: func(data []byte) (spi.OAuthClient, error) {
+			c := new(storeTestClient)
+			return c, json.Unmarshal(data, c)
+		},
+	}
+
+	s.Require().Nil(lookup.Save(ctx, &storeTestClient{Id: "foo", Name: "before"}))
+	s.Require().Nil(lookup.Save(ctx, &storeTestClient{Id: "foo", Name: "after"}))
+
+	client, err := lookup.FindById(ctx, "foo")
+	s.Require().Nil(err)
+	s.Assert().Equal("after", client.GetName())
+
+	s.Require().Nil(lookup.Delete(ctx, "foo"))
+	_, err = lookup.FindById(ctx, "foo")
+	s.Assert().NotNil(err)
+}
+
+func (s *StoreTestSuite) newTokenRequest() oauth.TokenRequest {
+	req := oauth.NewTokenRequest()
+	req.SetClient(s.client)
+	req.AddGrantTypes(spi.GrantTypeCode)
+	req.GetSession().SetSubject("test user")
+	req.GetSession().AddGrantedScopes("foo")
+	return req
+}
+
+// support: OAuthClient serializable as JSON
+type storeTestClient struct {
+	*test.MockClient `json:"-"`
+	Id               string `json:"id"`
+	Name             string `json:"name"`
+}
+
+func (c *storeTestClient) GetId() string {
+	return c.Id
+}
+
+func (c *storeTestClient) GetName() string {
+	return c.Name
+}
@@ -0,0 +1,53 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"time"
+)
+
+// internal accessor of a table holding serialized requests keyed by an identifier.
+type requestTable struct {
+	store *Store
+	name  string
+}
+
+func (t *requestTable) save(ctx context.Context, id string, req oauth.Request, ttl time.Duration) error {
+	kind, data, err := t.store.Codec.Encode(req)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.store.exec(ctx,
+		"INSERT INTO "+t.name+" (id, request_id, client_id, request_kind, data, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+		id, req.GetId(), req.GetClient().GetId(), kind, string(data), time.Now().Add(ttl).Unix())
+	return err
+}
+
+// Returns the request stored under the identifier, or nil if none was found or it has expired.
+func (t *requestTable) get(ctx context.Context, id string) (oauth.Request, error) {
+	var kind, clientId, data string
+
+	err := t.store.queryRow(ctx,
+		"SELECT request_kind, client_id, data FROM "+t.name+" WHERE id = ? AND expires_at > ?",
+		id, time.Now().Unix()).Scan(&kind, &clientId, &data)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	return t.store.Codec.Decode(ctx, kind, clientId, []byte(data))
+}
+
+func (t *requestTable) delete(ctx context.Context, id string) error {
+	_, err := t.store.exec(ctx, "DELETE FROM "+t.name+" WHERE id = ?", id)
+	return err
+}
+
+func (t *requestTable) deleteByRequestId(ctx context.Context, requestId string) error {
+	_, err := t.store.exec(ctx, "DELETE FROM "+t.name+" WHERE request_id = ?", requestId)
+	return err
+}
@@ -0,0 +1,135 @@
+package sqlstore
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"time"
+)
+
+var (
+	_ oauth.AccessTokenRepository  = (*AccessTokenRepository)(nil)
+	_ oauth.RefreshTokenRepository = (*RefreshTokenRepository)(nil)
+)
+
+const (
+	// Default time to live of access tokens.
+	DefaultAccessTokenTTL = 1 * time.Hour
+	// Default time to live of refresh tokens.
+	DefaultRefreshTokenTTL = 14 * 24 * time.Hour
+)
+
+// oauth.AccessTokenRepository backed by the oauth_access_token table. Tokens are stored under the identifier computed
+// by the Strategy, and expire after TTL, which should be no shorter than the lifespan configured on the
+// oauth.AccessTokenHelper.
+type AccessTokenRepository struct {
+	Strategy oauth.AccessTokenStrategy
+	TTL      time.Duration
+	table    *requestTable
+}
+
+// Create a new AccessTokenRepository whose tokens expire after ttl. A zero ttl defaults to DefaultAccessTokenTTL.
+func NewAccessTokenRepository(store *Store, strategy oauth.AccessTokenStrategy, ttl time.Duration) *AccessTokenRepository {
+	if ttl == 0 {
+		ttl = DefaultAccessTokenTTL
+	}
+	return &AccessTokenRepository{
+		Strategy: strategy,
+		TTL:      ttl,
+		table:    &requestTable{store: store, name: tableAccessToken},
+	}
+}
+
+func (r *AccessTokenRepository) Save(ctx context.Context, token string, req oauth.Request) error {
+	id, err := r.Strategy.ComputeIdentifier(token)
+	if err != nil {
+		return spi.AsOAuthError(err)
+	}
+	return r.table.save(ctx, id, req, r.TTL)
+}
+
+func (r *AccessTokenRepository) GetRequest(ctx context.Context, token string) (oauth.Request, error) {
+	id, err := r.Strategy.ComputeIdentifier(token)
+	if err != nil {
+		return nil, spi.ErrInvalidToken("access token is malformed.")
+	}
+
+	req, err := r.table.get(ctx, id)
+	switch {
+	case err != nil:
+		return nil, spi.AsOAuthError(err)
+	case req == nil:
+		return nil, spi.ErrInvalidToken("access token is unknown or expired.")
+	}
+
+	return req, nil
+}
+
+func (r *AccessTokenRepository) Delete(ctx context.Context, token string) error {
+	id, err := r.Strategy.ComputeIdentifier(token)
+	if err != nil {
+		return spi.AsOAuthError(err)
+	}
+	return r.table.delete(ctx, id)
+}
+
+func (r *AccessTokenRepository) DeleteByRequestId(ctx context.Context, requestId string) error {
+	return r.table.deleteByRequestId(ctx, requestId)
+}
+
+// oauth.RefreshTokenRepository backed by the oauth_refresh_token table. Tokens are stored under the identifier computed
+// by the Strategy, and expire after TTL.
+type RefreshTokenRepository struct {
+	Strategy oauth.RefreshTokenStrategy
+	TTL      time.Duration
+	table    *requestTable
+}
+
+// Create a new RefreshTokenRepository whose tokens expire after ttl. A zero ttl defaults to DefaultRefreshTokenTTL.
+func NewRefreshTokenRepository(store *Store, strategy oauth.RefreshTokenStrategy, ttl time.Duration) *RefreshTokenRepository {
+	if ttl == 0 {
+		ttl = DefaultRefreshTokenTTL
+	}
+	return &RefreshTokenRepository{
+		Strategy: strategy,
+		TTL:      ttl,
+		table:    &requestTable{store: store, name: tableRefreshToken},
+	}
+}
+
+func (r *RefreshTokenRepository) Save(ctx context.Context, token string, req oauth.Request) error {
+	id, err := r.Strategy.ComputeIdentifier(token)
+	if err != nil {
+		return spi.AsOAuthError(err)
+	}
+	return r.table.save(ctx, id, req, r.TTL)
+}
+
+func (r *RefreshTokenRepository) GetRequest(ctx context.Context, token string) (oauth.Request, error) {
+	id, err := r.Strategy.ComputeIdentifier(token)
+	if err != nil {
+		return nil, spi.ErrInvalidGrant("refresh token is malformed.")
+	}
+
+	req, err := r.table.get(ctx, id)
+	switch {
+	case err != nil:
+		return nil, spi.AsOAuthError(err)
+	case req == nil:
+		return nil, spi.ErrInvalidGrant("refresh token is unknown or expired.")
+	}
+
+	return req, nil
+}
+
+func (r *RefreshTokenRepository) Delete(ctx context.Context, token string) error {
+	id, err := r.Strategy.ComputeIdentifier(token)
+	if err != nil {
+		return spi.AsOAuthError(err)
+	}
+	return r.table.delete(ctx, id)
+}
+
+func (r *RefreshTokenRepository) DeleteByRequestId(ctx context.Context, requestId string) error {
+	return r.table.deleteByRequestId(ctx, requestId)
+}
@@ -1,10 +1,8 @@
 package oauth
 
 import (
-	"context"
 	"github.com/imulab-z/platform-sdk/spi"
 	"github.com/thoas/go-funk"
-	"sync"
 )
 
 // todo phase out
@@ -21,21 +19,22 @@ func Exactly(array []string, elements ...string) bool {
 	return true
 }
 
-// internal helper method to perform a error-capable action asynchronously
-func doAsync(ctx context.Context, wg *sync.WaitGroup, errChan chan error, action func() error) {
-	go func() {
-		defer wg.Done()
+// internal helper method to perform error-capable actions in order. The first failure skips the rest and is returned.
+// Actions are never run concurrently, as they share the request and response, which are not safe for concurrent use.
+func doAll(actions ...func() error) error {
+	for _, action := range actions {
 		if err := action(); err != nil {
-			select {
-			case <-ctx.Done():
-				return
-			case errChan <- err:
-				return
-			default:
-				return
-			}
+			return err
 		}
-	}()
+	}
+	return nil
+}
+
+// Returns true if the lookup error stems from the repository failing, rather than the token being unknown. Repositories
+// report unknown tokens with OAuthError typed errors other than server_error.
+func IsStorageFailure(err error) bool {
+	oauthErr, ok := err.(*spi.OAuthError)
+	return !ok || oauthErr.Err == "server_error"
 }
 
 // Returns true when the registered scopes of the client associated with the request accepts all the granted scopes
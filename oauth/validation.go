@@ -42,6 +42,10 @@ func (v *AuthorizeRequestValidator) Validate(ctx context.Context, req Request) e
 		return err
 	}
 
+	if err := ValidateCodeChallenge(authReq); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -50,7 +54,7 @@ func (v *AuthorizeRequestValidator) validateResponseTypes(req AuthorizeRequest)
 		return spi.ErrInvalidRequest("at least one response_type is required")
 	}
 
-	if !V(v.supportedResponseTypes()).Contains(req.GetResponseTypes()...) {
+	if !V(v.SupportedResponseTypes()).Contains(req.GetResponseTypes()...) {
 		return spi.ErrInvalidRequest("unsupported response_type")
 	}
 
@@ -63,7 +67,8 @@ func (v *AuthorizeRequestValidator) validateResponseTypes(req AuthorizeRequest)
 	return nil
 }
 
-func (v *AuthorizeRequestValidator) supportedResponseTypes() []string {
+// Returns the response types accepted by the validator.
+func (v *AuthorizeRequestValidator) SupportedResponseTypes() []string {
 	if len(v.ResponseTypesOverride) > 0 {
 		return v.ResponseTypesOverride
 	}
@@ -101,6 +106,10 @@ func (v *TokenRequestValidator) Validate(ctx context.Context, req Request) error
 		return err
 	}
 
+	if err := v.validateResourceOwnerCredentials(tokenReq); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -109,7 +118,7 @@ func (v *TokenRequestValidator) validateGrantType(req TokenRequest) error {
 		return spi.ErrInvalidRequest("at least one grant_type is required")
 	}
 
-	if !V(v.supportedGrantTypes()).Contains(req.GetGrantTypes()...) {
+	if !V(v.SupportedGrantTypes()).Contains(req.GetGrantTypes()...) {
 		return spi.ErrInvalidRequest("unsupported grant_type")
 	}
 
@@ -146,7 +155,20 @@ func (v *TokenRequestValidator) validateRefreshToken(req TokenRequest) error {
 	return nil
 }
 
-func (v *TokenRequestValidator) supportedGrantTypes() []string {
+func (v *TokenRequestValidator) validateResourceOwnerCredentials(req TokenRequest) error {
+	if !V(req.GetGrantTypes()).Contains(spi.GrantTypePassword) {
+		return nil
+	}
+
+	if len(req.GetUsername()) == 0 || len(req.GetPassword()) == 0 {
+		return spi.ErrInvalidRequest("username and password are required")
+	}
+
+	return nil
+}
+
+// Returns the grant types accepted by the validator.
+func (v *TokenRequestValidator) SupportedGrantTypes() []string {
 	if len(v.GrantTypesOverride) > 0 {
 		return v.GrantTypesOverride
 	}
@@ -155,7 +177,9 @@ func (v *TokenRequestValidator) supportedGrantTypes() []string {
 		spi.GrantTypeImplicit,
 		spi.GrantTypeClient,
 		spi.GrantTypeRefresh,
-		// there is no plan to support password grant_type for now, hence not included
+		// password grant_type is only kept for legacy first-party clients, it still needs to be registered by the
+		// client and handled by PasswordHandler.
+		spi.GrantTypePassword,
 	}
 }
 
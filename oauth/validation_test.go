@@ -58,6 +58,17 @@ func TestTokenRequestValidator_Validate(t *testing.T) {
 			},
 			expectsError: true,
 		},
+		{
+			name: "missing password on grant_type=password",
+			reqFunc: func() Request {
+				r := NewTokenRequest()
+				r.SetClient(new(validatorTestClient))
+				r.AddGrantTypes(spi.GrantTypePassword)
+				r.SetUsername("alice")
+				return r
+			},
+			expectsError: true,
+		},
 		{
 			name: "pass validation",
 			reqFunc: func() Request {
@@ -142,5 +153,5 @@ func (v *validatorTestClient) GetResponseTypes() []string {
 }
 
 func (v *validatorTestClient) GetGrantTypes() []string {
-	return []string{spi.GrantTypeCode, spi.GrantTypeRefresh}
+	return []string{spi.GrantTypeCode, spi.GrantTypeRefresh, spi.GrantTypePassword}
 }
@@ -5,9 +5,15 @@ import (
 	"github.com/imulab-z/platform-sdk/oauth"
 	"github.com/imulab-z/platform-sdk/spi"
 	"net/http"
+	"sort"
 )
 
-// Main entry point for token endpoint authentication
+var (
+	_ oauth.ClientAuthentication = (*AuthenticationHandler)(nil)
+)
+
+// Main entry point for token endpoint authentication. It implements oauth.ClientAuthentication itself so that it can be
+// plugged in wherever a single authentication method is expected (i.e. the oauth.RequestParser).
 type AuthenticationHandler struct {
 	Authenticators	map[string]oauth.ClientAuthentication
 	ClientLookup 	spi.ClientLookup
@@ -47,6 +53,32 @@ func (h *AuthenticationHandler) Authenticate(ctx context.Context, r *http.Reques
 	}
 }
 
+// Returns the token_endpoint_auth_method values of the registered authenticators, in sorted order.
+func (h *AuthenticationHandler) SupportedMethods() []string {
+	methods := make([]string, 0, len(h.Authenticators))
+	for method := range h.Authenticators {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// AuthenticationHandler is a composite of authentication methods and does not represent any single method, hence
+// returns an empty string.
+func (h *AuthenticationHandler) Method() string {
+	return ""
+}
+
+// Returns true if any of the configured authenticators supports the request.
+func (h *AuthenticationHandler) Supports(r *http.Request) bool {
+	for _, auth := range h.Authenticators {
+		if auth.Supports(r) {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *AuthenticationHandler) tryOidcClient(ctx context.Context, r *http.Request) spi.OidcClient {
 	if err := r.ParseForm(); err != nil {
 		return nil
@@ -0,0 +1,79 @@
+package oidc
+
+import (
+	"encoding/json"
+	"github.com/imulab-z/platform-sdk/spi"
+	"reflect"
+)
+
+// The claims request parameter, as defined in Open ID Connect Core 1.0 Section 5.5. Members are keyed by claim name,
+// which may carry a language tag (i.e. name#ja-Kana-JP). A nil ClaimRequest means the claim is requested in the default
+// manner.
+type ClaimsRequest struct {
+	UserInfo map[string]*ClaimRequest `json:"userinfo,omitempty"`
+	IdToken  map[string]*ClaimRequest `json:"id_token,omitempty"`
+}
+
+// Request for an individual claim.
+type ClaimRequest struct {
+	Essential bool          `json:"essential,omitempty"`
+	Value     interface{}   `json:"value,omitempty"`
+	Values    []interface{} `json:"values,omitempty"`
+}
+
+// Parse the JSON value of the claims parameter. Returns an invalid_request error if the value is malformed.
+func ParseClaimsRequest(raw string) (*ClaimsRequest, error) {
+	cr := new(ClaimsRequest)
+	if err := json.Unmarshal([]byte(raw), cr); err != nil {
+		return nil, spi.ErrInvalidRequest("claims parameter is malformed.")
+	}
+	return cr, nil
+}
+
+// Returns true if the claim value satisfies the value or values constraint of the request, if any.
+func (r *ClaimRequest) Accepts(v interface{}) bool {
+	if r == nil {
+		return true
+	}
+
+	if r.Value != nil && !reflect.DeepEqual(r.Value, v) {
+		return false
+	}
+
+	if len(r.Values) > 0 {
+		for _, candidate := range r.Values {
+			if reflect.DeepEqual(candidate, v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// Returns the claims requested by the members, looked up from all claims in the preferred locales. Claims whose value
+// is rejected by the request are omitted.
+func selectRequestedClaims(all map[string]interface{}, members map[string]*ClaimRequest, locales []string) map[string]interface{} {
+	selected := make(map[string]interface{})
+	for name, request := range members {
+		if key, v, ok := lookupClaim(all, name, locales); ok && request.Accepts(v) {
+			selected[key] = v
+		}
+	}
+	return selected
+}
+
+// Lookup the claim in the first available of the preferred locales, or the untagged claim as a fallback. Claim names
+// which already carry a language tag are looked up as is. Returns the key under which the claim was found.
+func lookupClaim(all map[string]interface{}, name string, locales []string) (string, interface{}, bool) {
+	for _, locale := range locales {
+		key := name + "#" + locale
+		if v, ok := all[key]; ok {
+			return key, v, true
+		}
+	}
+
+	v, ok := all[name]
+	return name, v, ok
+}
@@ -0,0 +1,73 @@
+package oidc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseClaimsRequest(t *testing.T) {
+	cr, err := ParseClaimsRequest(`{
+		"userinfo": {
+			"given_name": {"essential": true},
+			"nickname": null,
+			"email": {"essential": true},
+			"picture": null
+		},
+		"id_token": {
+			"auth_time": {"essential": true},
+			"acr": {"values": ["urn:mace:incommon:iap:silver"]}
+		}
+	}`)
+	assert.Nil(t, err)
+	assert.Len(t, cr.UserInfo, 4)
+	assert.True(t, cr.UserInfo["given_name"].Essential)
+	assert.Nil(t, cr.UserInfo["nickname"])
+	assert.Equal(t, []interface{}{"urn:mace:incommon:iap:silver"}, cr.IdToken["acr"].Values)
+
+	_, err = ParseClaimsRequest(`{"userinfo":`)
+	assert.NotNil(t, err)
+}
+
+func TestSelectRequestedClaims(t *testing.T) {
+	all := map[string]interface{}{
+		"name":            "John Doe",
+		"name#ja-Kana-JP": "ジョン",
+		"family_name":     "Doe",
+		"email":           "john@test.org",
+		"email_verified":  true,
+	}
+
+	for _, v := range []struct {
+		name    string
+		members map[string]*ClaimRequest
+		locales []string
+		expect  map[string]interface{}
+	}{
+		{
+			name:    "default",
+			members: map[string]*ClaimRequest{"name": nil, "email": {Essential: true}, "phone_number": nil},
+			expect:  map[string]interface{}{"name": "John Doe", "email": "john@test.org"},
+		},
+		{
+			name:    "preferred locale",
+			members: map[string]*ClaimRequest{"name": nil, "family_name": nil},
+			locales: []string{"ja-Kana-JP", "en"},
+			expect:  map[string]interface{}{"name#ja-Kana-JP": "ジョン", "family_name": "Doe"},
+		},
+		{
+			name:    "language tagged claim",
+			members: map[string]*ClaimRequest{"name#ja-Kana-JP": nil},
+			expect:  map[string]interface{}{"name#ja-Kana-JP": "ジョン"},
+		},
+		{
+			name: "value constraints",
+			members: map[string]*ClaimRequest{
+				"email_verified": {Value: true},
+				"email":          {Values: []interface{}{"jane@test.org"}},
+			},
+			expect: map[string]interface{}{"email_verified": true},
+		},
+	} {
+		assert.Equal(t, v.expect, selectRequestedClaims(all, v.members, v.locales), v.name)
+	}
+}
@@ -0,0 +1,20 @@
+package oidc
+
+import "github.com/imulab-z/platform-sdk/oauth"
+
+const (
+	// Type discriminators of the request and session implementations of this package.
+	CodecTypeRequest          = "oidc.request"
+	CodecTypeAuthorizeRequest = "oidc.authorize_request"
+	CodecTypeTokenRequest     = "oidc.token_request"
+	CodecTypeSession          = "oidc.session"
+)
+
+// Register the request and session implementations of this package with the codec. Clients looked up by the codec
+// must implement spi.OidcClient to be accepted by these requests.
+func RegisterCodecTypes(codec *oauth.RequestCodec) {
+	codec.RegisterRequest(CodecTypeRequest, NewRequest)
+	codec.RegisterRequest(CodecTypeAuthorizeRequest, func() oauth.Request { return NewAuthorizeRequest() })
+	codec.RegisterRequest(CodecTypeTokenRequest, func() oauth.Request { return NewTokenRequest() })
+	codec.RegisterSession(CodecTypeSession, func() oauth.Session { return NewSession() })
+}
@@ -0,0 +1,109 @@
+package oidc
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/memstore"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/imulab-z/platform-sdk/test"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCodec(t *testing.T) {
+	s := new(CodecTestSuite)
+	suite.Run(t, s)
+}
+
+type CodecTestSuite struct {
+	suite.Suite
+	client *test.MockClient
+	codec  *oauth.RequestCodec
+}
+
+func (s *CodecTestSuite) SetupTest() {
+	s.client = new(test.MockClient)
+	lookup := new(test.MockClientLookup)
+	lookup.On("FindById", s.client.GetId()).Return(s.client, nil)
+	s.codec = oauth.NewRequestCodec(lookup)
+	RegisterCodecTypes(s.codec)
+}
+
+func (s *CodecTestSuite) TestAuthorizeRequest() {
+	authTime := time.Now().Truncate(time.Second)
+
+	req := NewAuthorizeRequest()
+	req.SetClient(s.client)
+	req.AddResponseTypes(spi.ResponseTypeCode, spi.ResponseTypeIdToken)
+	req.SetNonce("abc")
+	req.SetMaxAge(3600)
+	req.SetClaims(&ClaimsRequest{IdToken: map[string]*ClaimRequest{"email": {Essential: true}}})
+	req.AddClaimsLocale("ja-Kana-JP")
+	req.GetSession().SetSubject("test user")
+	req.GetSession().(Session).SetNonce("abc")
+	req.GetSession().(Session).SetAuthTime(authTime)
+
+	data, err := s.codec.Encode(req)
+	s.Require().Nil(err)
+
+	decoded, err := s.codec.Decode(context.Background(), data)
+	s.Require().Nil(err)
+
+	authReq, ok := decoded.(AuthorizeRequest)
+	s.Require().True(ok)
+	s.Assert().Equal(s.client.GetId(), authReq.GetClient().GetId())
+	s.Assert().Equal("abc", authReq.GetNonce())
+	s.Assert().Equal(uint64(3600), authReq.GetMaxAge())
+	s.Assert().True(authReq.GetClaims().IdToken["email"].Essential)
+
+	session, ok := authReq.GetSession().(Session)
+	s.Require().True(ok)
+	s.Assert().Equal("test user", session.GetSubject())
+	s.Assert().Equal("abc", session.GetNonce())
+	s.Assert().Equal(authTime.Unix(), session.GetAuthTime().Unix())
+	s.Assert().True(session.GetClaimsRequest().IdToken["email"].Essential)
+	s.Assert().Equal([]string{"ja-Kana-JP"}, session.GetClaimsLocales())
+}
+
+func (s *CodecTestSuite) TestOAuthSessionRejected() {
+	req := oauth.NewTokenRequest()
+	req.GetSession().SetSubject("test user")
+	data, err := s.codec.Encode(req)
+	s.Require().Nil(err)
+
+	// swap the request type while leaving the oauth session in place
+	data = []byte(strings.Replace(string(data), oauth.CodecTypeTokenRequest, CodecTypeTokenRequest, 1))
+
+	_, err = s.codec.Decode(context.Background(), data)
+	s.Assert().NotNil(err)
+}
+
+func (s *CodecTestSuite) TestMemstoreSnapshot() {
+	repo := memstore.NewAccessTokenRepository(0)
+	repo.Requests = s.codec
+
+	req := NewTokenRequest()
+	req.SetClient(s.client)
+	req.GetSession().SetSubject("test user")
+	s.Require().Nil(repo.Save(context.Background(), "a", req))
+
+	// concurrent userinfo requests obfuscating the subject of their own copy
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, err := repo.GetRequest(context.Background(), "a")
+			s.Assert().Nil(err)
+			found.GetSession().(Session).SetObfuscatedSubject("pairwise user")
+		}()
+	}
+	wg.Wait()
+
+	found, err := repo.GetRequest(context.Background(), "a")
+	s.Require().Nil(err)
+	s.Assert().Empty(found.GetSession().(Session).GetObfuscatedSubject())
+}
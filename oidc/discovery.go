@@ -0,0 +1,211 @@
+package oidc
+
+import (
+	"encoding/json"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"gopkg.in/square/go-jose.v2"
+	"net/http"
+	"strings"
+)
+
+const (
+	// Well known path to serve the discovery document, relative to the issuer.
+	DiscoveryPath = "/.well-known/openid-configuration"
+	// Subject types
+	SubjectTypePublic   = "public"
+	SubjectTypePairwise = "pairwise"
+)
+
+// Builds the discovery document from the configured components, so that the advertised capabilities follow the actual
+// configuration:
+//
+// response_types_supported and grant_types_supported are collected from handlers implementing
+// oauth.ResponseTypesAdvertiser and oauth.GrantTypesAdvertiser, and narrowed down by the validators;
+// response_modes_supported and display_values_supported come from the AuthorizeValidator;
+// token_endpoint_auth_methods_supported comes from the Authentication;
+// id_token_signing_alg_values_supported and userinfo_signing_alg_values_supported come from the signing keys of
+// KeySource and Jwks, as published by oauth.PublishedJwks.
+//
+// Any of the above is only derived when its source component is configured, otherwise the value in Base is kept. All
+// other fields (i.e. issuer and endpoints) are taken from Base as is.
+type DiscoveryBuilder struct {
+	Base               spi.Discovery
+	AuthorizeHandlers  []oauth.AuthorizeHandler
+	TokenHandlers      []oauth.TokenHandler
+	AuthorizeValidator *AuthorizeRequestValidator
+	TokenValidator     *TokenRequestValidator
+	Authentication     oauth.ClientAuthentication
+	Jwks               *jose.JSONWebKeySet
+	KeySource          oauth.SigningKeySource
+}
+
+func (b *DiscoveryBuilder) Build() *spi.Discovery {
+	d := b.Base
+
+	if responseTypes := b.responseTypes(); len(responseTypes) > 0 {
+		d.ResponseTypesSupported = responseTypes
+	}
+
+	if grantTypes := b.grantTypes(); len(grantTypes) > 0 {
+		d.GrantTypesSupported = grantTypes
+	}
+
+	if b.AuthorizeValidator != nil {
+		d.ResponseModesSupported = b.AuthorizeValidator.SupportedResponseModes()
+		d.DisplayValuesSupported = b.AuthorizeValidator.SupportedDisplayValues()
+	}
+
+	if methods := b.authMethods(); len(methods) > 0 {
+		d.TokenEndpointAuthMethodsSupported = methods
+	}
+
+	if algs := b.signingAlgs(); len(algs) > 0 {
+		d.IdTokenSigningAlgSupported = algs
+		d.UserInfoSigningAlgSupported = algs
+	}
+
+	if len(d.SubjectTypesSupported) == 0 {
+		d.SubjectTypesSupported = []string{SubjectTypePublic}
+	}
+
+	if len(d.ClaimsSupported) == 0 && len(d.ScopesSupported) > 0 {
+		d.ClaimsSupported = b.claims(d.ScopesSupported)
+	}
+
+	return &d
+}
+
+// Returns a http.Handler serving the built discovery document.
+func (b *DiscoveryBuilder) Handler() http.Handler {
+	return &DiscoveryHandler{Discovery: b.Build()}
+}
+
+func (b *DiscoveryBuilder) responseTypes() []string {
+	values := newOrderedSet()
+	for _, h := range b.AuthorizeHandlers {
+		if advertiser, ok := h.(oauth.ResponseTypesAdvertiser); ok {
+			for _, rt := range advertiser.SupportedResponseTypes() {
+				if b.AuthorizeValidator == nil ||
+					oauth.V(b.AuthorizeValidator.SupportedResponseTypes()).Contains(strings.Split(rt, " ")...) {
+					values.add(rt)
+				}
+			}
+		}
+	}
+	return values.items
+}
+
+func (b *DiscoveryBuilder) grantTypes() []string {
+	advertisers := make([]oauth.GrantTypesAdvertiser, 0)
+	for _, h := range b.AuthorizeHandlers {
+		if advertiser, ok := h.(oauth.GrantTypesAdvertiser); ok {
+			advertisers = append(advertisers, advertiser)
+		}
+	}
+	for _, h := range b.TokenHandlers {
+		if advertiser, ok := h.(oauth.GrantTypesAdvertiser); ok {
+			advertisers = append(advertisers, advertiser)
+		}
+	}
+
+	values := newOrderedSet()
+	for _, advertiser := range advertisers {
+		for _, gt := range advertiser.SupportedGrantTypes() {
+			if b.TokenValidator == nil || oauth.V(b.TokenValidator.SupportedGrantTypes()).Contains(gt) {
+				values.add(gt)
+			}
+		}
+	}
+	return values.items
+}
+
+func (b *DiscoveryBuilder) authMethods() []string {
+	switch {
+	case b.Authentication == nil:
+		return nil
+	case len(b.Authentication.Method()) > 0:
+		return []string{b.Authentication.Method()}
+	default:
+		if composite, ok := b.Authentication.(*AuthenticationHandler); ok {
+			return composite.SupportedMethods()
+		}
+		return nil
+	}
+}
+
+// JWS algorithms a server key may be advertised for.
+var jwsAlgorithms = []string{
+	spi.SignAlgHS256, spi.SignAlgHS384, spi.SignAlgHS512,
+	spi.SignAlgRS256, spi.SignAlgRS384, spi.SignAlgRS512,
+	spi.SignAlgES256, spi.SignAlgES384, spi.SignAlgES512,
+	spi.SignAlgPS256, spi.SignAlgPS384, spi.SignAlgPS512,
+}
+
+// Returns the algorithms of the published keys that can be used for signing. Keys not labelled with a use are only
+// considered when their algorithm is a JWS algorithm, so that unlabelled encryption keys are not advertised.
+func (b *DiscoveryBuilder) signingAlgs() []string {
+	if b.Jwks == nil && b.KeySource == nil {
+		return nil
+	}
+
+	values := newOrderedSet()
+	for _, key := range oauth.PublishedJwks(b.KeySource, b.Jwks).Keys {
+		if key.Use != "enc" && oauth.V(jwsAlgorithms).Contains(key.Algorithm) {
+			values.add(key.Algorithm)
+		}
+	}
+	return values.items
+}
+
+// Returns the 'sub' claim and the claims released by the scopes.
+func (b *DiscoveryBuilder) claims(scopes []string) []string {
+	values := newOrderedSet()
+	values.add("sub")
+	for _, scope := range scopes {
+		for _, claim := range ScopeClaims[scope] {
+			values.add(claim)
+		}
+	}
+	return values.items
+}
+
+// Serves the discovery document at DiscoveryPath.
+type DiscoveryHandler struct {
+	Discovery *spi.Discovery
+}
+
+func (h *DiscoveryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	raw, err := json.Marshal(h.Discovery)
+	if err != nil {
+		_ = oauth.WriteTokenError(w, spi.ErrServerError(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeJson)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(raw)
+}
+
+// Insertion ordered set of strings.
+type orderedSet struct {
+	items []string
+	seen  map[string]struct{}
+}
+
+func newOrderedSet() *orderedSet {
+	return &orderedSet{items: make([]string, 0), seen: make(map[string]struct{})}
+}
+
+func (s *orderedSet) add(item string) {
+	if _, ok := s.seen[item]; !ok {
+		s.seen[item] = struct{}{}
+		s.items = append(s.items, item)
+	}
+}
@@ -0,0 +1,120 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDiscoveryBuilder_Build(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	builder := &DiscoveryBuilder{
+		Base: spi.Discovery{
+			Issuer:                "https://test.org",
+			AuthorizationEndpoint: "https://test.org/oauth/authorize",
+			TokenEndpoint:         "https://test.org/oauth/token",
+			ScopesSupported:       []string{spi.ScopeOpenId, spi.ScopeEmail},
+		},
+		AuthorizeHandlers: []oauth.AuthorizeHandler{
+			new(oauth.AuthorizeCodeHandler),
+			new(AuthorizeCodeHandler),
+			new(ImplicitHandler),
+			new(HybridHandler),
+		},
+		TokenHandlers: []oauth.TokenHandler{
+			new(oauth.AuthorizeCodeHandler),
+			new(oauth.RefreshHandler),
+			new(RefreshHandler),
+		},
+		AuthorizeValidator: &AuthorizeRequestValidator{
+			AuthorizeRequestValidator: &oauth.AuthorizeRequestValidator{},
+		},
+		TokenValidator: &TokenRequestValidator{
+			TokenRequestValidator: &oauth.TokenRequestValidator{},
+		},
+		Authentication: &AuthenticationHandler{
+			Authenticators: map[string]oauth.ClientAuthentication{
+				spi.AuthMethodNone:              new(oauth.NoneAuthentication),
+				spi.AuthMethodClientSecretBasic: new(oauth.ClientSecretBasicAuthentication),
+			},
+		},
+		Jwks: &jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{
+				{Key: privateKey, Algorithm: spi.SignAlgRS256, Use: "sig", KeyID: "sig-key"},
+				{Key: &privateKey.PublicKey, Algorithm: spi.EncryptAlgRSAOAEP, Use: "enc", KeyID: "enc-key"},
+				{Key: &privateKey.PublicKey, Algorithm: spi.EncryptAlgRSAOAEP, KeyID: "unlabelled-enc-key"},
+				{Key: privateKey, Algorithm: spi.SignAlgPS256, KeyID: "unlabelled-sig-key"},
+			},
+		},
+	}
+
+	d := builder.Build()
+	assert.Equal(t, "https://test.org", d.Issuer)
+	assert.Equal(t, []string{
+		"code",
+		"id_token",
+		"id_token token",
+		"code token",
+		"code id_token",
+		"code id_token token",
+	}, d.ResponseTypesSupported)
+	assert.Equal(t, []string{spi.GrantTypeCode, spi.GrantTypeImplicit, spi.GrantTypeRefresh}, d.GrantTypesSupported)
+	assert.Contains(t, d.ResponseModesSupported, spi.ResponseModeFormPost)
+	assert.NotEmpty(t, d.DisplayValuesSupported)
+	assert.Equal(t, []string{spi.AuthMethodClientSecretBasic, spi.AuthMethodNone}, d.TokenEndpointAuthMethodsSupported)
+	assert.Equal(t, []string{spi.SignAlgRS256, spi.SignAlgPS256}, d.IdTokenSigningAlgSupported)
+	assert.Equal(t, []string{SubjectTypePublic}, d.SubjectTypesSupported)
+	assert.Equal(t, []string{"sub", "email", "email_verified"}, d.ClaimsSupported)
+
+	// restricted validator narrows down advertised capabilities
+	builder.AuthorizeValidator.ResponseTypesOverride = []string{spi.ResponseTypeCode}
+	builder.TokenValidator.GrantTypesOverride = []string{spi.GrantTypeCode}
+	d = builder.Build()
+	assert.Equal(t, []string{"code"}, d.ResponseTypesSupported)
+	assert.Equal(t, []string{spi.GrantTypeCode}, d.GrantTypesSupported)
+}
+
+func TestDiscoveryBuilder_KeySource(t *testing.T) {
+	manager, err := oauth.NewKeyManager(oauth.NewRsaKeyGenerator(jose.PS256, 2048), time.Hour, time.Hour)
+	assert.Nil(t, err)
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	builder := &DiscoveryBuilder{
+		KeySource: manager,
+		Jwks: &jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{
+				{Key: privateKey, Algorithm: spi.SignAlgRS256, Use: "sig", KeyID: "sig-key"},
+			},
+		},
+	}
+
+	// rotated keys are advertised along with the static keys used for the other algorithms
+	assert.Equal(t, []string{string(jose.PS256), spi.SignAlgRS256}, builder.Build().IdTokenSigningAlgSupported)
+}
+
+func TestDiscoveryHandler(t *testing.T) {
+	h := (&DiscoveryBuilder{Base: spi.Discovery{Issuer: "https://test.org"}}).Handler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, DiscoveryPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	d := new(spi.Discovery)
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(d))
+	assert.Equal(t, "https://test.org", d.Issuer)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, DiscoveryPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
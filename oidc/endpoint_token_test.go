@@ -0,0 +1,108 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTokenEndpoint(t *testing.T) {
+	s := new(TokenEndpointTestSuite)
+	suite.Run(t, s)
+}
+
+// Test suite to verify oauth.TokenEndpoint orchestrates the Open ID Connect authorization code flow, which relies on
+// oauth.AuthorizeCodeHandler and AuthorizeCodeHandler being run in the two phase order.
+type TokenEndpointTestSuite struct {
+	suite.Suite
+	handlers AuthorizeCodeHandlerTestSuite
+	endpoint *oauth.TokenEndpoint
+}
+
+func (s *TokenEndpointTestSuite) SetupTest() {
+	s.handlers.SetupTest()
+
+	lookup := new(tokenEndpointTestSuiteClientLookup)
+	s.endpoint = &oauth.TokenEndpoint{
+		RequestFactory: func() oauth.TokenRequest {
+			return NewTokenRequest()
+		},
+		Parser: oauth.NewHttpRequestParser(lookup, &AuthenticationHandler{
+			ClientLookup: lookup,
+			Authenticators: map[string]oauth.ClientAuthentication{
+				spi.AuthMethodNone: &oauth.NoneAuthentication{Lookup: lookup},
+			},
+		}),
+		Validator: &TokenRequestValidator{
+			TokenRequestValidator: &oauth.TokenRequestValidator{
+				RequestValidator: &oauth.RequestValidator{},
+			},
+		},
+		Handlers: []oauth.TokenHandler{
+			s.handlers.oauthHandler,
+			s.handlers.oidcHandler,
+		},
+	}
+}
+
+func (s *TokenEndpointTestSuite) TestHandleTokenRequest() {
+	ctx := context.Background()
+
+	req := NewAuthorizeRequest()
+	req.SetId(uuid.NewV4().String())
+	req.AddResponseTypes(spi.ResponseTypeCode)
+	req.AddScopes("foo", spi.ScopeOpenId)
+	req.SetRedirectUri("http://test.org/callback")
+	req.SetClient(new(tokenEndpointTestSuiteClient))
+	req.GetSession().SetSubject("test user")
+	req.GetSession().AddGrantedScopes("foo", spi.ScopeOpenId)
+	req.GetSession().(Session).SetObfuscatedSubject("test user")
+
+	resp := oauth.NewResponse()
+	s.Require().Nil(s.handlers.Authorize(ctx, req, resp))
+
+	f := url.Values{}
+	f.Set(spi.ParamClientId, new(tokenEndpointTestSuiteClient).GetId())
+	f.Set(spi.ParamGrantType, spi.GrantTypeCode)
+	f.Set(spi.ParamCode, resp.GetString(oauth.Code))
+	f.Set(spi.ParamRedirectUri, "http://test.org/callback")
+	r := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(f.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	tokenReq, tokenResp, err := s.endpoint.HandleTokenRequest(ctx, r)
+	s.Assert().Nil(err)
+	s.Assert().Equal("test user", tokenReq.GetSession().GetSubject())
+	s.Assert().NotEmpty(tokenResp.GetString(oauth.AccessToken))
+	s.Assert().NotEmpty(tokenResp.GetString(IdToken))
+}
+
+// support: spi.ClientLookup
+type tokenEndpointTestSuiteClientLookup struct{}
+
+func (l *tokenEndpointTestSuiteClientLookup) FindById(ctx context.Context, id string) (spi.OAuthClient, error) {
+	if c := new(tokenEndpointTestSuiteClient); c.GetId() == id {
+		return c, nil
+	}
+	return nil, errors.New("not found")
+}
+
+// support: public OidcClient
+type tokenEndpointTestSuiteClient struct {
+	authorizeCodeHandlerTestSuiteClient
+}
+
+func (c *tokenEndpointTestSuiteClient) GetType() string {
+	return spi.ClientTypePublic
+}
+
+func (c *tokenEndpointTestSuiteClient) GetTokenEndpointAuthMethod() string {
+	return spi.AuthMethodNone
+}
@@ -93,3 +93,15 @@ func (h *HybridHandler) SupportsAuthorizeRequest(req oauth.AuthorizeRequest) boo
 		return false
 	}
 }
+
+func (h *HybridHandler) SupportedResponseTypes() []string {
+	return []string{
+		spi.ResponseTypeCode + " " + spi.ResponseTypeToken,
+		spi.ResponseTypeCode + " " + spi.ResponseTypeIdToken,
+		spi.ResponseTypeCode + " " + spi.ResponseTypeIdToken + " " + spi.ResponseTypeToken,
+	}
+}
+
+func (h *HybridHandler) SupportedGrantTypes() []string {
+	return []string{spi.GrantTypeCode, spi.GrantTypeImplicit}
+}
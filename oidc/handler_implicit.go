@@ -89,3 +89,14 @@ func (h *ImplicitHandler) SupportsAuthorizeRequest(req oauth.AuthorizeRequest) b
 		return false
 	}
 }
+
+func (h *ImplicitHandler) SupportedResponseTypes() []string {
+	return []string{
+		spi.ResponseTypeIdToken,
+		spi.ResponseTypeIdToken + " " + spi.ResponseTypeToken,
+	}
+}
+
+func (h *ImplicitHandler) SupportedGrantTypes() []string {
+	return []string{spi.GrantTypeImplicit}
+}
@@ -5,16 +5,12 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base64"
-	"encoding/json"
-	"errors"
-	"fmt"
 	"github.com/imulab-z/platform-sdk/oauth"
 	"github.com/imulab-z/platform-sdk/spi"
 	"github.com/satori/go.uuid"
 	"gopkg.in/square/go-jose.v2"
 	"gopkg.in/square/go-jose.v2/jwt"
 	"hash"
-	"strings"
 	"time"
 )
 
@@ -23,10 +19,16 @@ type IdTokenStrategy interface {
 	NewToken(ctx context.Context, req oauth.Request) (string, error)
 }
 
+// IdTokenStrategy which signs id_token with the server key matching the client's id_token_signed_response_alg, and
+// optionally encrypts it to the client's json web key set. When a KeySource (i.e. oauth.KeyManager) is set, its active
+// key is used for the algorithms it covers, and the key in Jwks for the others. When ClaimsProvider is set, end-user
+// claims requested in the id_token member of the claims request parameter are included.
 type JwxIdTokenStrategy struct {
-	Issuer        string
-	TokenLifespan time.Duration
-	Jwks		  *jose.JSONWebKeySet
+	Issuer         string
+	TokenLifespan  time.Duration
+	Jwks		   *jose.JSONWebKeySet
+	KeySource      oauth.SigningKeySource
+	ClaimsProvider spi.ClaimsProvider
 }
 
 func (s *JwxIdTokenStrategy) NewToken(ctx context.Context, req oauth.Request) (string, error) {
@@ -40,7 +42,15 @@ func (s *JwxIdTokenStrategy) NewToken(ctx context.Context, req oauth.Request) (s
 		panic("must supply an OidcClient")
 	}
 
-	tok, err := s.sign(s.createClaims(sess, client), client)
+	claims := s.createClaims(sess, client)
+
+	if requested, err := s.requestedClaims(ctx, sess); err != nil {
+		return "", err
+	} else if len(requested) > 0 {
+		claims = append(claims, requested)
+	}
+
+	tok, err := s.sign(claims, client)
 	if err != nil {
 		return "", err
 	}
@@ -54,102 +64,21 @@ func (s *JwxIdTokenStrategy) NewToken(ctx context.Context, req oauth.Request) (s
 }
 
 func (s *JwxIdTokenStrategy) encrypt(raw string, client spi.OidcClient) (string, error) {
-	encrypter, err := s.createEncrypter(client)
-	if err != nil {
-		return "", err
-	}
-
-	if obj, err := encrypter.Encrypt([]byte(raw)); err != nil {
-		return "", spi.ErrServerError(fmt.Errorf("failed to encrypt id_token: %s", err.Error()))
-	} else {
-		return obj.CompactSerialize()
-	}
-}
-
-func (s *JwxIdTokenStrategy) createEncrypter(client spi.OidcClient) (jose.Encrypter, error) {
-	// assuming client jwks is supplied or resolved.
-	if len(client.GetJwks()) == 0 {
-		return nil, spi.ErrServerError(errors.New("missing client json web key set"))
-	}
-
-	jwks := new(jose.JSONWebKeySet)
-	if err := json.NewDecoder(strings.NewReader(client.GetJwks())).Decode(jwks); err != nil {
-		return nil, spi.ErrServerError(fmt.Errorf("invalid client json web key set: %s", err.Error()))
-	}
-
-	key := oauth.FindEncryptionKeyByAlg(jwks, client.GetIdTokenEncryptedResponseAlg())
-	if key == nil {
-		return nil, spi.ErrServerError(errors.New("cannot find key to encrypt id_token for client"))
-	}
-
-	return jose.NewEncrypter(
-		jose.ContentEncryption(client.GetIdTokenEncryptedResponseEnc()),
-		jose.Recipient{
-			Algorithm: jose.KeyAlgorithm(client.GetIdTokenEncryptedResponseAlg()),
-			Key:       key,
-			KeyID:     key.KeyID,
-		},
-		nil,
+	return encryptForClient(
+		raw,
+		client,
+		client.GetIdTokenEncryptedResponseAlg(),
+		client.GetIdTokenEncryptedResponseEnc(),
+		"id_token",
 	)
 }
 
 func (s *JwxIdTokenStrategy) sign(claims []interface{}, client spi.OidcClient) (string, error) {
 	switch client.GetIdTokenSignedResponseAlg() {
 	case spi.SignAlgNone:
-		m := make(map[string]interface{})
-		// merge claims from different sources
-		for _, c := range claims {
-			switch c.(type) {
-			case map[string]interface{}:
-				for k, v := range c.(map[string]interface{}) {
-					m[k] = v
-				}
-			case *jwt.Claims:
-				if cb, err := json.Marshal(c); err != nil {
-					return "", err
-				} else if err := json.Unmarshal(cb, &m); err != nil {
-					return "", err
-				}
-			default:
-				return "", errors.New("unknown internal claim type")
-			}
-		}
-		// encode
-		if mb, err := json.Marshal(m); err != nil {
-			return "", err
-		} else {
-			return string(mb), nil
-		}
+		return mergeClaims(claims)
 	default:
-		signer, err := s.createSigner(client)
-		if err != nil {
-			return "", err
-		}
-
-		b := jwt.Signed(signer)
-		for _, c := range claims {
-			b = b.Claims(c)
-		}
-
-		return b.CompactSerialize()
-	}
-}
-
-func (s *JwxIdTokenStrategy) createSigner(client spi.OidcClient) (jose.Signer, error) {
-	key := oauth.FindSigningKeyByAlg(s.Jwks, client.GetIdTokenSignedResponseAlg())
-	if key == nil {
-		return nil, spi.ErrServerError(errors.New("cannot find key to sign id_token for client"))
-	}
-
-	opt := (&jose.SignerOptions{}).WithType("JWT")
-
-	if signer, err := jose.NewSigner(jose.SigningKey{
-		Algorithm: jose.SignatureAlgorithm(client.GetIdTokenSignedResponseAlg()),
-		Key:       key,
-	}, opt); err != nil {
-		return nil, spi.ErrServerError(fmt.Errorf("failed to setup id_token signer: %s", err.Error()))
-	} else {
-		return signer, nil
+		return signClaims(s.KeySource, s.Jwks, client.GetIdTokenSignedResponseAlg(), claims, "id_token")
 	}
 }
 
@@ -159,7 +88,7 @@ func (s *JwxIdTokenStrategy) createClaims(session Session, client spi.OidcClient
 	claims = append(claims, &jwt.Claims{
 		ID:        uuid.NewV4().String(),
 		Issuer:    s.Issuer,
-		Subject:   session.GetObfuscatedSubject(),
+		Subject:   subjectOf(session),
 		Audience:  []string{client.GetId()},
 		NotBefore: jwt.NewNumericDate(time.Now()),
 		IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -189,8 +118,31 @@ func (s *JwxIdTokenStrategy) createClaims(session Session, client spi.OidcClient
 	return claims
 }
 
+// Returns the end-user claims requested in the id_token member of the claims request parameter.
+func (s *JwxIdTokenStrategy) requestedClaims(ctx context.Context, session Session) (map[string]interface{}, error) {
+	if s.ClaimsProvider == nil || session.GetClaimsRequest() == nil || len(session.GetClaimsRequest().IdToken) == 0 {
+		return nil, nil
+	}
+
+	all, err := s.ClaimsProvider.GetClaims(ctx, session.GetSubject())
+	if err != nil {
+		return nil, spi.AsOAuthError(err)
+	}
+
+	requested := selectRequestedClaims(all, session.GetClaimsRequest().IdToken, session.GetClaimsLocales())
+	// registered claims are managed by the strategy
+	for _, name := range []string{"sub", "iss", "aud", "exp", "iat", "nbf", "jti", "auth_time", "nonce", "acr"} {
+		delete(requested, name)
+	}
+
+	return requested, nil
+}
+
+// Generates id_token through the Strategy. When SubjectObfuscator is set, the subject presented to the client is
+// computed before generation.
 type IdTokenHelper struct {
-	Strategy IdTokenStrategy
+	Strategy          IdTokenStrategy
+	SubjectObfuscator *SubjectObfuscator
 }
 
 func (h *IdTokenHelper) GenToken(ctx context.Context, req oauth.Request, resp oauth.Response) error {
@@ -204,6 +156,10 @@ func (h *IdTokenHelper) GenToken(ctx context.Context, req oauth.Request, resp oa
 		panic("must be called with oidc.Session")
 	}
 
+	if err := obfuscateSessionSubject(ctx, h.SubjectObfuscator, client, sess); err != nil {
+		return err
+	}
+
 	for k, v := range map[string]string{
 		oauth.Code:        "c_hash",
 		oauth.AccessToken: "at_hash",
@@ -106,6 +106,32 @@ func (s *JwxIdTokenStrategyTestSuite) TestEncryptOnlyIdToken() {
 	s.Assert().NotEmpty(tok)
 }
 
+func (s *JwxIdTokenStrategyTestSuite) TestRequestedClaims() {
+	s.strategy.ClaimsProvider = new(userInfoHandlerTestSuiteClaimsProvider)
+
+	claimsRequest, err := ParseClaimsRequest(`{"id_token":{"email":{"essential":true},"name":null}}`)
+	s.Require().Nil(err)
+
+	req := NewAuthorizeRequest()
+	req.SetClaims(claimsRequest)
+	req.AddClaimsLocale("ja-Kana-JP")
+	req.GetSession().SetSubject("test user")
+
+	client := new(jwxIdTokenStrategyTestSuiteOnlyClient)
+	client.RequireIdTokenSigning = false
+	client.RequireIdTokenEncryption = false
+	req.SetClient(client)
+
+	tok, err := s.strategy.NewToken(context.Background(), req)
+	s.Require().Nil(err)
+
+	claims := make(map[string]interface{})
+	s.Require().Nil(json.Unmarshal([]byte(tok), &claims))
+	s.Assert().Equal("test user", claims["sub"])
+	s.Assert().Equal("john@test.org", claims["email"])
+	s.Assert().Equal("ジョン", claims["name#ja-Kana-JP"])
+}
+
 type jwxIdTokenStrategyTestSuiteOnlyClient struct {
 	*test.PanicClient
 	jwks						string
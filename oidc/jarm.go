@@ -0,0 +1,170 @@
+package oidc
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/satori/go.uuid"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// Name of the parameter carrying the JWT secured authorization response.
+	JarmResponse = "response"
+	// Default lifespan of a JWT secured authorization response, short lived as recommended by JARM Section 2.1.
+	DefaultJarmLifespan = 10 * time.Minute
+)
+
+// Returns true if the response mode is one of the JWT Secured Authorization Response Modes (JARM).
+func IsJarmResponseMode(mode string) bool {
+	switch mode {
+	case spi.ResponseModeJwt, spi.ResponseModeQueryJwt, spi.ResponseModeFragmentJwt, spi.ResponseModeFormPostJwt:
+		return true
+	default:
+		return false
+	}
+}
+
+type JarmStrategy interface {
+	// Encode the authorization response parameters into a JWT secured authorization response.
+	NewResponse(ctx context.Context, req oauth.AuthorizeRequest, params url.Values) (string, error)
+}
+
+// JarmStrategy which signs the response with the server key registered for the client's
+// authorization_signed_response_alg (RS256 by default) and, if requested, encrypts it to the client's json web key
+// set. It shares the signing and encryption routines with JwxIdTokenStrategy, including the use of KeySource. Responses
+// expire after TokenLifespan (DefaultJarmLifespan by default).
+type JwxJarmStrategy struct {
+	Issuer        string
+	TokenLifespan time.Duration
+	Jwks          *jose.JSONWebKeySet
+	KeySource     oauth.SigningKeySource
+}
+
+func (s *JwxJarmStrategy) NewResponse(ctx context.Context, req oauth.AuthorizeRequest, params url.Values) (string, error) {
+	client, ok := req.GetClient().(spi.OidcClient)
+	if !ok {
+		panic("must supply an OidcClient")
+	}
+
+	signAlg, encryptAlg, encryptEnc := s.algorithms(client)
+	if signAlg == spi.SignAlgNone {
+		return "", spi.ErrServerErrorf("%s is not allowed for authorization response", spi.SignAlgNone)
+	}
+
+	tok, err := signClaims(s.KeySource, s.Jwks, signAlg, s.createClaims(client, params), "authorization response")
+	if err != nil {
+		return "", err
+	}
+
+	if encryptAlg != spi.EncryptAlgNone {
+		return encryptForClient(tok, client, encryptAlg, encryptEnc, "authorization response")
+	}
+
+	return tok, nil
+}
+
+// Returns the signing algorithm, encryption algorithm and content encryption algorithm for the client, with defaults
+// applied for anything not registered.
+func (s *JwxJarmStrategy) algorithms(client spi.OidcClient) (signAlg string, encryptAlg string, encryptEnc string) {
+	signAlg, encryptAlg, encryptEnc = spi.SignAlgRS256, spi.EncryptAlgNone, spi.EncAlgNone
+
+	if jarm, ok := client.(spi.JarmAware); ok {
+		if len(jarm.GetAuthorizationSignedResponseAlg()) > 0 {
+			signAlg = jarm.GetAuthorizationSignedResponseAlg()
+		}
+		if len(jarm.GetAuthorizationEncryptedResponseAlg()) > 0 &&
+			jarm.GetAuthorizationEncryptedResponseAlg() != spi.EncryptAlgNone {
+			encryptAlg = jarm.GetAuthorizationEncryptedResponseAlg()
+			encryptEnc = spi.EncAlgA128CBCHS256
+			if len(jarm.GetAuthorizationEncryptedResponseEnc()) > 0 {
+				encryptEnc = jarm.GetAuthorizationEncryptedResponseEnc()
+			}
+		}
+	}
+
+	return
+}
+
+func (s *JwxJarmStrategy) createClaims(client spi.OidcClient, params url.Values) []interface{} {
+	extra := make(map[string]interface{})
+	for k := range params {
+		extra[k] = params.Get(k)
+	}
+
+	lifespan := s.TokenLifespan
+	if lifespan == 0 {
+		lifespan = DefaultJarmLifespan
+	}
+
+	return []interface{}{
+		&jwt.Claims{
+			ID:       uuid.NewV4().String(),
+			Issuer:   s.Issuer,
+			Audience: []string{client.GetId()},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(lifespan)),
+		},
+		extra,
+	}
+}
+
+// Renders authorize responses and errors. Requests with a JWT Secured Authorization Response Mode have their response
+// parameters wrapped into a single 'response' parameter produced by the Strategy, which is then delivered using the
+// base response mode. Other requests are rendered as is by oauth.WriteAuthorizeResponse and oauth.WriteAuthorizeError.
+type AuthorizeResponseWriter struct {
+	Strategy JarmStrategy
+}
+
+func (w *AuthorizeResponseWriter) WriteAuthorizeResponse(ctx context.Context, rw http.ResponseWriter,
+	req oauth.AuthorizeRequest, resp oauth.Response) error {
+	mode := oauth.ResponseModeOf(req)
+	if !IsJarmResponseMode(mode) {
+		return oauth.WriteAuthorizeResponse(rw, req, resp)
+	}
+	return w.writeJarm(ctx, rw, req, mode, oauth.AuthorizeResponseValues(req, resp))
+}
+
+func (w *AuthorizeResponseWriter) WriteAuthorizeError(ctx context.Context, rw http.ResponseWriter,
+	req oauth.AuthorizeRequest, err error) error {
+	if req == nil || len(req.GetRedirectUri()) == 0 || req.GetClient() == nil {
+		return oauth.WriteAuthorizeError(rw, req, err)
+	}
+
+	mode := oauth.ResponseModeOf(req)
+	if !IsJarmResponseMode(mode) {
+		return oauth.WriteAuthorizeError(rw, req, err)
+	}
+	return w.writeJarm(ctx, rw, req, mode, oauth.AuthorizeErrorValues(req, spi.AsOAuthError(err)))
+}
+
+func (w *AuthorizeResponseWriter) writeJarm(ctx context.Context, rw http.ResponseWriter,
+	req oauth.AuthorizeRequest, mode string, params url.Values) error {
+	tok, err := w.Strategy.NewResponse(ctx, req, params)
+	if err != nil {
+		return err
+	}
+
+	wrapped := url.Values{}
+	wrapped.Set(JarmResponse, tok)
+
+	return oauth.WriteAuthorizeRedirect(rw, req.GetRedirectUri(), jarmBaseResponseMode(req, mode), wrapped)
+}
+
+// Returns the response mode used to deliver the JWT secured authorization response. The 'jwt' shortcut resolves to
+// query.jwt for the 'code' response type alone and fragment.jwt otherwise.
+func jarmBaseResponseMode(req oauth.AuthorizeRequest, mode string) string {
+	switch mode {
+	case spi.ResponseModeQueryJwt:
+		return spi.ResponseModeQuery
+	case spi.ResponseModeFragmentJwt:
+		return spi.ResponseModeFragment
+	case spi.ResponseModeFormPostJwt:
+		return spi.ResponseModeFormPost
+	default:
+		return oauth.DefaultResponseModeOf(req)
+	}
+}
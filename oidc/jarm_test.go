@@ -0,0 +1,260 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/imulab-z/platform-sdk/test"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthorizeResponseWriter(t *testing.T) {
+	s := new(AuthorizeResponseWriterTestSuite)
+	suite.Run(t, s)
+}
+
+type AuthorizeResponseWriterTestSuite struct {
+	suite.Suite
+	serverKey *rsa.PrivateKey
+	clientKey *rsa.PrivateKey
+	writer    *AuthorizeResponseWriter
+}
+
+func (s *AuthorizeResponseWriterTestSuite) SetupTest() {
+	var err error
+
+	s.serverKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().Nil(err)
+
+	s.clientKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().Nil(err)
+
+	s.writer = &AuthorizeResponseWriter{
+		Strategy: &JwxJarmStrategy{
+			Issuer:        "test",
+			TokenLifespan: 10 * time.Minute,
+			Jwks: &jose.JSONWebKeySet{
+				Keys: []jose.JSONWebKey{
+					{
+						Key:       s.serverKey,
+						Algorithm: string(jose.RS256),
+						Use:       "sig",
+						KeyID:     "test-key",
+					},
+				},
+			},
+		},
+	}
+}
+
+func (s *AuthorizeResponseWriterTestSuite) TestQueryJwtCodeResponse() {
+	req := s.newRequest(spi.ResponseModeQueryJwt, false, spi.ResponseTypeCode)
+
+	w := httptest.NewRecorder()
+	err := s.writer.WriteAuthorizeResponse(context.Background(), w, req, oauth.Response{oauth.Code: "some-code"})
+	s.Assert().Nil(err)
+	s.Assert().Equal(http.StatusFound, w.Code)
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	s.Require().Nil(err)
+	s.Assert().Empty(location.Query().Get(oauth.Code))
+
+	claims := s.verify(location.Query().Get(JarmResponse))
+	s.Assert().Equal("test", claims["iss"])
+	s.Assert().Equal([]interface{}{new(jarmTestClient).GetId()}, claims["aud"])
+	s.Assert().NotNil(claims["exp"])
+	s.Assert().Equal("some-code", claims[oauth.Code])
+	s.Assert().Equal("12345678", claims[oauth.State])
+}
+
+func (s *AuthorizeResponseWriterTestSuite) TestJwtTokenResponseDefaultsToFragment() {
+	req := s.newRequest(spi.ResponseModeJwt, false, spi.ResponseTypeToken)
+
+	w := httptest.NewRecorder()
+	err := s.writer.WriteAuthorizeResponse(context.Background(), w, req, oauth.Response{oauth.AccessToken: "some-token"})
+	s.Assert().Nil(err)
+	s.Assert().Equal(http.StatusFound, w.Code)
+
+	parts := strings.SplitN(w.Header().Get("Location"), "#", 2)
+	s.Require().Len(parts, 2)
+	fragment, err := url.ParseQuery(parts[1])
+	s.Require().Nil(err)
+
+	claims := s.verify(fragment.Get(JarmResponse))
+	s.Assert().Equal("some-token", claims[oauth.AccessToken])
+}
+
+func (s *AuthorizeResponseWriterTestSuite) TestEncryptedResponse() {
+	req := s.newRequest(spi.ResponseModeQueryJwt, true, spi.ResponseTypeCode)
+
+	w := httptest.NewRecorder()
+	err := s.writer.WriteAuthorizeResponse(context.Background(), w, req, oauth.Response{oauth.Code: "some-code"})
+	s.Assert().Nil(err)
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	s.Require().Nil(err)
+
+	jwe, err := jose.ParseEncrypted(location.Query().Get(JarmResponse))
+	s.Require().Nil(err)
+	raw, err := jwe.Decrypt(s.clientKey)
+	s.Require().Nil(err)
+
+	claims := s.verify(string(raw))
+	s.Assert().Equal("some-code", claims[oauth.Code])
+}
+
+func (s *AuthorizeResponseWriterTestSuite) TestFormPostJwtError() {
+	req := s.newRequest(spi.ResponseModeFormPostJwt, false, spi.ResponseTypeCode)
+
+	w := httptest.NewRecorder()
+	err := s.writer.WriteAuthorizeError(context.Background(), w, req, spi.ErrAccessDenied("user denied"))
+	s.Assert().Nil(err)
+	s.Assert().Equal(http.StatusOK, w.Code)
+	s.Assert().Contains(w.Body.String(), `name="response"`)
+	s.Assert().NotContains(w.Body.String(), "access_denied")
+}
+
+func (s *AuthorizeResponseWriterTestSuite) TestUnsupportedResponseModeError() {
+	req := s.newRequest("bogus", false, spi.ResponseTypeCode)
+
+	w := httptest.NewRecorder()
+	err := s.writer.WriteAuthorizeError(context.Background(), w, req, spi.ErrInvalidRequest("response_mode is not supported."))
+	s.Assert().Nil(err)
+	s.Assert().Equal(http.StatusFound, w.Code)
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	s.Require().Nil(err)
+	s.Assert().Equal("invalid_request", location.Query().Get("error"))
+}
+
+func (s *AuthorizeResponseWriterTestSuite) TestPlainResponseMode() {
+	req := s.newRequest(spi.ResponseModeQuery, false, spi.ResponseTypeCode)
+
+	w := httptest.NewRecorder()
+	err := s.writer.WriteAuthorizeResponse(context.Background(), w, req, oauth.Response{oauth.Code: "some-code"})
+	s.Assert().Nil(err)
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	s.Require().Nil(err)
+	s.Assert().Equal("some-code", location.Query().Get(oauth.Code))
+	s.Assert().Empty(location.Query().Get(JarmResponse))
+}
+
+func (s *AuthorizeResponseWriterTestSuite) TestDefaultLifespan() {
+	s.writer.Strategy.(*JwxJarmStrategy).TokenLifespan = 0
+	req := s.newRequest(spi.ResponseModeQueryJwt, false, spi.ResponseTypeCode)
+
+	w := httptest.NewRecorder()
+	s.Require().Nil(s.writer.WriteAuthorizeResponse(context.Background(), w, req, oauth.Response{oauth.Code: "some-code"}))
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	s.Require().Nil(err)
+	claims := s.verify(location.Query().Get(JarmResponse))
+	s.Assert().InDelta(float64(time.Now().Add(DefaultJarmLifespan).Unix()), claims["exp"], 5)
+}
+
+func (s *AuthorizeResponseWriterTestSuite) TestKeyRotation() {
+	manager, err := oauth.NewKeyManager(oauth.NewRsaKeyGenerator(jose.RS256, 2048), time.Hour, time.Hour)
+	s.Require().Nil(err)
+	s.writer.Strategy.(*JwxJarmStrategy).KeySource = manager
+
+	kids := make([]string, 0)
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		req := s.newRequest(spi.ResponseModeQueryJwt, false, spi.ResponseTypeCode)
+		s.Require().Nil(s.writer.WriteAuthorizeResponse(context.Background(), w, req, oauth.Response{oauth.Code: "some-code"}))
+
+		location, err := url.Parse(w.Header().Get("Location"))
+		s.Require().Nil(err)
+		tok, err := jwt.ParseSigned(location.Query().Get(JarmResponse))
+		s.Require().Nil(err)
+
+		// signed with the active key, which is published
+		kid := tok.Headers[0].KeyID
+		s.Assert().Equal(manager.SigningKey(string(jose.RS256)).KeyID, kid)
+		s.Assert().NotEmpty(oauth.PublishedJwks(manager, nil).Key(kid))
+		claims := make(map[string]interface{})
+		s.Require().Nil(tok.Claims(manager.VerificationKey(kid), &claims))
+		s.Assert().Equal("some-code", claims[oauth.Code])
+
+		kids = append(kids, kid)
+		s.Require().Nil(manager.Rotate())
+	}
+	s.Assert().NotEqual(kids[0], kids[1])
+}
+
+func (s *AuthorizeResponseWriterTestSuite) newRequest(mode string, encrypt bool, responseTypes ...string) AuthorizeRequest {
+	req := NewAuthorizeRequest()
+	req.SetClient(&jarmTestClient{key: s.clientKey, encrypt: encrypt})
+	req.AddResponseTypes(responseTypes...)
+	req.SetRedirectUri("https://test.org/callback")
+	req.SetState("12345678")
+	req.SetResponseMode(mode)
+	return req
+}
+
+func (s *AuthorizeResponseWriterTestSuite) verify(raw string) map[string]interface{} {
+	tok, err := jwt.ParseSigned(raw)
+	s.Require().Nil(err)
+
+	claims := make(map[string]interface{})
+	s.Require().Nil(tok.Claims(&s.serverKey.PublicKey, &claims))
+	return claims
+}
+
+// support: OidcClient registered for JARM
+type jarmTestClient struct {
+	*test.PanicClient
+	key     *rsa.PrivateKey
+	encrypt bool
+}
+
+func (c *jarmTestClient) GetId() string {
+	return "b9ca2c52-1b53-4ed3-9a5c-1c2e2c6d0b2b"
+}
+
+func (c *jarmTestClient) GetJwks() string {
+	jwks, err := json.Marshal(&jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{
+				Algorithm: spi.EncryptAlgRSAOAEP,
+				KeyID:     "3e3e7b4e-4f5b-4b4a-8a44-3e8c1a9a2f1d",
+				Key:       &c.key.PublicKey,
+				Use:       "enc",
+			},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return string(jwks)
+}
+
+func (c *jarmTestClient) GetAuthorizationSignedResponseAlg() string {
+	return spi.SignAlgRS256
+}
+
+func (c *jarmTestClient) GetAuthorizationEncryptedResponseAlg() string {
+	if c.encrypt {
+		return spi.EncryptAlgRSAOAEP
+	}
+	return ""
+}
+
+func (c *jarmTestClient) GetAuthorizationEncryptedResponseEnc() string {
+	if c.encrypt {
+		return spi.EncAlgA128GCM
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"strings"
+)
+
+// Sign the claims with the server key for the algorithm, taken from the source if it has one, or from jwks otherwise
+// (see oauth.FindSigningKey). The claims may be a mix of *jwt.Claims and map[string]interface{}. The subject describes
+// the token being signed (i.e. id_token) and only appears in errors.
+func signClaims(source oauth.SigningKeySource, jwks *jose.JSONWebKeySet, alg string, claims []interface{},
+	subject string) (string, error) {
+	return signClaimsWithKey(oauth.FindSigningKey(source, jwks, alg), alg, claims, subject)
+}
+
+// Sign the claims with the key. A nil key results in an error.
+func signClaimsWithKey(key *jose.JSONWebKey, alg string, claims []interface{}, subject string) (string, error) {
+	signer, err := createSigner(key, alg, subject)
+	if err != nil {
+		return "", err
+	}
+
+	b := jwt.Signed(signer)
+	for _, c := range claims {
+		b = b.Claims(c)
+	}
+
+	return b.CompactSerialize()
+}
+
+// Merge the claims into a plain JSON object, used when the 'none' signing algorithm is requested.
+func mergeClaims(claims []interface{}) (string, error) {
+	m := make(map[string]interface{})
+	for _, c := range claims {
+		switch c.(type) {
+		case map[string]interface{}:
+			for k, v := range c.(map[string]interface{}) {
+				m[k] = v
+			}
+		case *jwt.Claims:
+			if cb, err := json.Marshal(c); err != nil {
+				return "", err
+			} else if err := json.Unmarshal(cb, &m); err != nil {
+				return "", err
+			}
+		default:
+			return "", errors.New("unknown internal claim type")
+		}
+	}
+
+	if mb, err := json.Marshal(m); err != nil {
+		return "", err
+	} else {
+		return string(mb), nil
+	}
+}
+
+func createSigner(key *jose.JSONWebKey, alg string, subject string) (jose.Signer, error) {
+	if key == nil {
+		return nil, spi.ErrServerError(fmt.Errorf("cannot find key to sign %s for client", subject))
+	}
+
+	opt := (&jose.SignerOptions{}).WithType("JWT")
+
+	if signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.SignatureAlgorithm(alg),
+		Key:       key,
+	}, opt); err != nil {
+		return nil, spi.ErrServerError(fmt.Errorf("failed to setup %s signer: %s", subject, err.Error()))
+	} else {
+		return signer, nil
+	}
+}
+
+// Encrypt the raw content to the client using the key from the client's registered json web key set for the
+// algorithm. The subject describes the content being encrypted (i.e. id_token) and only appears in errors.
+func encryptForClient(raw string, client spi.OidcClient, alg string, enc string, subject string) (string, error) {
+	encrypter, err := createEncrypter(client, alg, enc, subject)
+	if err != nil {
+		return "", err
+	}
+
+	if obj, err := encrypter.Encrypt([]byte(raw)); err != nil {
+		return "", spi.ErrServerError(fmt.Errorf("failed to encrypt %s: %s", subject, err.Error()))
+	} else {
+		return obj.CompactSerialize()
+	}
+}
+
+func createEncrypter(client spi.OidcClient, alg string, enc string, subject string) (jose.Encrypter, error) {
+	// assuming client jwks is supplied or resolved.
+	if len(client.GetJwks()) == 0 {
+		return nil, spi.ErrServerError(errors.New("missing client json web key set"))
+	}
+
+	jwks := new(jose.JSONWebKeySet)
+	if err := json.NewDecoder(strings.NewReader(client.GetJwks())).Decode(jwks); err != nil {
+		return nil, spi.ErrServerError(fmt.Errorf("invalid client json web key set: %s", err.Error()))
+	}
+
+	key := oauth.FindEncryptionKeyByAlg(jwks, alg)
+	if key == nil {
+		return nil, spi.ErrServerError(fmt.Errorf("cannot find key to encrypt %s for client", subject))
+	}
+
+	return jose.NewEncrypter(
+		jose.ContentEncryption(enc),
+		jose.Recipient{
+			Algorithm: jose.KeyAlgorithm(alg),
+			Key:       key,
+			KeyID:     key.KeyID,
+		},
+		nil,
+	)
+}
@@ -0,0 +1,189 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/imulab-z/platform-sdk/crypt"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Prefix of the request_uri handles issued by the pushed authorization request endpoint (RFC 9126 Section 2.2).
+	PushedRequestUriPrefix = "urn:ietf:params:oauth:request_uri:"
+	// Default lifespan of a pushed authorization request.
+	DefaultPushedRequestLifespan = 60 * time.Second
+)
+
+var (
+	_ oauth.AuthorizeParameterResolver = (*PushedAuthorizationResolver)(nil)
+)
+
+// Authorization parameters pushed by a client.
+type PushedAuthorization struct {
+	ClientId  string
+	Values    url.Values
+	ExpiresAt time.Time
+}
+
+// Repository for pushed authorization requests, keyed by their request_uri handle.
+type PushedAuthorizationRepository interface {
+	// Save the pushed authorization under the handle.
+	Save(ctx context.Context, requestUri string, par *PushedAuthorization) error
+	// Get and remove the pushed authorization under the handle, so that it can only be used once. Returns a nil
+	// PushedAuthorization if none was found.
+	Consume(ctx context.Context, requestUri string) (*PushedAuthorization, error)
+}
+
+// Entry point of the pushed authorization request endpoint (RFC 9126). Clients authenticate with the Authentication
+// and push the authorization parameters as a form post. The parameters are parsed into an AuthorizeRequest with the
+// Parser and validated with the Validator, just like an ordinary authorize request. Upon success, the parameters are
+// saved in the Repository under a one-time request_uri handle, which expires after Lifespan (DefaultPushedRequestLifespan
+// when zero), and the handle is returned to the client with 201 Created.
+//
+// The authorize endpoint parser must be equipped with a PushedAuthorizationResolver for the handle to be accepted, while
+// the Parser of this endpoint must not, as pushed requests are never made by reference to another pushed request.
+type PushedAuthorizationEndpoint struct {
+	Authentication oauth.ClientAuthentication
+	Parser         oauth.RequestParser
+	Validator      *AuthorizeRequestValidator
+	Repository     PushedAuthorizationRepository
+	Lifespan       time.Duration
+}
+
+// Response of the pushed authorization request endpoint.
+type PushedAuthorizationResponse struct {
+	RequestUri string `json:"request_uri"`
+	ExpiresIn  int64  `json:"expires_in"`
+}
+
+// Client authentication parameters which are not part of the authorization request.
+var clientAuthenticationParams = []string{spi.ParamClientSecret, spi.ParamClientAssertion, spi.ParamClientAssertionType}
+
+func (e *PushedAuthorizationEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	client, values, err := oauth.ParseAuthenticatedForm(r.Context(), r, e.Authentication)
+	if err != nil {
+		_ = oauth.WriteTokenError(w, err)
+		return
+	}
+
+	resp, err := e.Push(r.Context(), client, values)
+	if err != nil {
+		_ = oauth.WriteTokenError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeJson)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// Validate and save the authorization parameters pushed by the authenticated client.
+func (e *PushedAuthorizationEndpoint) Push(ctx context.Context, client spi.OAuthClient, values url.Values) (
+	*PushedAuthorizationResponse, error) {
+	if len(values.Get(spi.ParamRequestUri)) > 0 {
+		return nil, spi.ErrInvalidRequest("request_uri must not be pushed.")
+	}
+
+	if clientId := values.Get(spi.ParamClientId); len(clientId) > 0 && clientId != client.GetId() {
+		return nil, spi.ErrInvalidRequest("client_id mismatch with authenticated client.")
+	}
+
+	pushed := url.Values{}
+	for k, v := range values {
+		if !oauth.V(clientAuthenticationParams).Contains(k) {
+			pushed[k] = v
+		}
+	}
+	pushed.Set(spi.ParamClientId, client.GetId())
+
+	if err := e.validate(ctx, pushed); err != nil {
+		return nil, err
+	}
+
+	handle, err := crypt.RandomBytes(32)
+	if err != nil {
+		return nil, spi.ErrServerError(err)
+	}
+	requestUri := PushedRequestUriPrefix + base64.RawURLEncoding.EncodeToString(handle)
+
+	lifespan := e.Lifespan
+	if lifespan == 0 {
+		lifespan = DefaultPushedRequestLifespan
+	}
+
+	if err := e.Repository.Save(ctx, requestUri, &PushedAuthorization{
+		ClientId:  client.GetId(),
+		Values:    pushed,
+		ExpiresAt: time.Now().Add(lifespan),
+	}); err != nil {
+		return nil, spi.AsOAuthError(err)
+	}
+
+	return &PushedAuthorizationResponse{
+		RequestUri: requestUri,
+		ExpiresIn:  int64(lifespan / time.Second),
+	}, nil
+}
+
+// Run the parameters through the Parser and Validator, as if they were sent to the authorize endpoint.
+func (e *PushedAuthorizationEndpoint) validate(ctx context.Context, values url.Values) error {
+	r, err := http.NewRequest(http.MethodGet, "/?"+values.Encode(), nil)
+	if err != nil {
+		return spi.ErrServerError(err)
+	}
+
+	req := NewAuthorizeRequest()
+	if err := e.Parser.ParseAuthorizeRequest(ctx, r.WithContext(ctx), req); err != nil {
+		return spi.AsOAuthError(err)
+	}
+
+	return e.Validator.Validate(ctx, req)
+}
+
+// oauth.AuthorizeParameterResolver which replaces a request_uri handle issued by the PushedAuthorizationEndpoint with the
+// pushed parameters. The handle is consumed upon resolution, whether it succeeds or not. Other request_uri values are
+// left to the next resolver, hence this resolver should come before RequestObjectResolver.
+//
+// When RequirePushedAuthorization is set, authorization requests not using a handle are refused.
+type PushedAuthorizationResolver struct {
+	Repository                 PushedAuthorizationRepository
+	RequirePushedAuthorization bool
+}
+
+func (r *PushedAuthorizationResolver) ResolveAuthorizeParameters(ctx context.Context, client spi.OAuthClient,
+	values url.Values) (url.Values, error) {
+	requestUri := values.Get(spi.ParamRequestUri)
+	if !strings.HasPrefix(requestUri, PushedRequestUriPrefix) {
+		if r.RequirePushedAuthorization {
+			return nil, spi.ErrInvalidRequest("authorization request must be pushed.")
+		}
+		return values, nil
+	}
+
+	par, err := r.Repository.Consume(ctx, requestUri)
+	switch {
+	case err != nil:
+		return nil, spi.AsOAuthError(err)
+	case par == nil:
+		return nil, spi.ErrInvalidRequestUri("request_uri is unknown or has been used.")
+	case time.Now().After(par.ExpiresAt):
+		return nil, spi.ErrInvalidRequestUri("request_uri has expired.")
+	case par.ClientId != client.GetId():
+		return nil, spi.ErrInvalidRequestUri("request_uri was not pushed by client.")
+	}
+
+	resolved := url.Values{}
+	for k, v := range par.Values {
+		resolved[k] = v
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,212 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/imulab-z/platform-sdk/test"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPushedAuthorization(t *testing.T) {
+	s := new(PushedAuthorizationTestSuite)
+	suite.Run(t, s)
+}
+
+type PushedAuthorizationTestSuite struct {
+	suite.Suite
+	client     *parTestClient
+	repository *inMemPushedAuthorizationRepo
+	endpoint   *PushedAuthorizationEndpoint
+	parser     oauth.RequestParser
+}
+
+func (s *PushedAuthorizationTestSuite) SetupTest() {
+	s.client = &parTestClient{id: "foo"}
+	s.repository = &inMemPushedAuthorizationRepo{store: make(map[string]*PushedAuthorization)}
+
+	lookup := new(test.MockClientLookup)
+	lookup.On("FindById", "foo").Return(s.client, nil)
+
+	s.endpoint = &PushedAuthorizationEndpoint{
+		Authentication: &parTestAuthentication{client: s.client},
+		Parser:         oauth.NewHttpRequestParser(lookup, nil),
+		Validator: &AuthorizeRequestValidator{
+			AuthorizeRequestValidator: &oauth.AuthorizeRequestValidator{
+				RequestValidator: &oauth.RequestValidator{},
+			},
+		},
+		Repository: s.repository,
+	}
+	s.parser = oauth.NewHttpRequestParser(lookup, nil, &PushedAuthorizationResolver{Repository: s.repository})
+}
+
+func (s *PushedAuthorizationTestSuite) TestPushAndAuthorize() {
+	form := url.Values{}
+	form.Set(spi.ParamClientId, "foo")
+	form.Set(spi.ParamClientSecret, "s3cret")
+	form.Set(spi.ParamResponseType, spi.ResponseTypeCode)
+	form.Set(spi.ParamRedirectUri, "https://foo.com/callback")
+	form.Set(spi.ParamScope, "openid")
+	form.Set(spi.ParamState, "12345678")
+
+	rw := httptest.NewRecorder()
+	s.endpoint.ServeHTTP(rw, s.formRequest(form))
+	s.Require().Equal(http.StatusCreated, rw.Code)
+
+	resp := new(PushedAuthorizationResponse)
+	s.Require().Nil(json.NewDecoder(rw.Body).Decode(resp))
+	s.Assert().True(strings.HasPrefix(resp.RequestUri, PushedRequestUriPrefix))
+	s.Assert().Equal(int64(60), resp.ExpiresIn)
+
+	authorize := func() (oauth.AuthorizeRequest, error) {
+		query := url.Values{}
+		query.Set(spi.ParamClientId, "foo")
+		query.Set(spi.ParamRequestUri, resp.RequestUri)
+		req := NewAuthorizeRequest()
+		r := httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+query.Encode(), nil)
+		return req, s.parser.ParseAuthorizeRequest(context.Background(), r, req)
+	}
+
+	req, err := authorize()
+	s.Require().Nil(err)
+	s.Assert().Equal([]string{spi.ResponseTypeCode}, req.GetResponseTypes())
+	s.Assert().Equal("12345678", req.GetState())
+	s.Assert().Equal("https://foo.com/callback", req.GetRedirectUri())
+
+	// handle is one-time
+	_, err = authorize()
+	s.Require().NotNil(err)
+	s.Assert().Equal("invalid_request_uri", err.(*spi.OAuthError).Err)
+}
+
+func (s *PushedAuthorizationTestSuite) TestPushInvalid() {
+	for _, v := range []struct {
+		name   string
+		form   url.Values
+		expect string
+	}{
+		{
+			name: "unsupported response_type",
+			form: url.Values{
+				spi.ParamResponseType: {spi.ResponseTypeToken},
+				spi.ParamRedirectUri:  {"https://foo.com/callback"},
+			},
+			expect: "invalid_request",
+		},
+		{
+			name: "pushed request_uri",
+			form: url.Values{
+				spi.ParamResponseType: {spi.ResponseTypeCode},
+				spi.ParamRequestUri:   {PushedRequestUriPrefix + "foo"},
+			},
+			expect: "invalid_request",
+		},
+		{
+			name: "client_id mismatch",
+			form: url.Values{
+				spi.ParamClientId:     {"bar"},
+				spi.ParamResponseType: {spi.ResponseTypeCode},
+			},
+			expect: "invalid_request",
+		},
+	} {
+		_, err := s.endpoint.Push(context.Background(), s.client, v.form)
+		s.Require().NotNil(err, v.name)
+		s.Assert().Equal(v.expect, err.(*spi.OAuthError).Err, v.name)
+	}
+	s.Assert().Empty(s.repository.store)
+}
+
+func (s *PushedAuthorizationTestSuite) TestResolveExpired() {
+	s.repository.store[PushedRequestUriPrefix+"expired"] = &PushedAuthorization{
+		ClientId:  "foo",
+		Values:    url.Values{spi.ParamResponseType: {spi.ResponseTypeCode}},
+		ExpiresAt: time.Now().Add(-time.Second),
+	}
+
+	resolver := &PushedAuthorizationResolver{Repository: s.repository}
+	_, err := resolver.ResolveAuthorizeParameters(context.Background(), s.client, url.Values{
+		spi.ParamClientId:   {"foo"},
+		spi.ParamRequestUri: {PushedRequestUriPrefix + "expired"},
+	})
+	s.Require().NotNil(err)
+	s.Assert().Equal("invalid_request_uri", err.(*spi.OAuthError).Err)
+}
+
+func (s *PushedAuthorizationTestSuite) TestResolveRequired() {
+	resolver := &PushedAuthorizationResolver{Repository: s.repository, RequirePushedAuthorization: true}
+	_, err := resolver.ResolveAuthorizeParameters(context.Background(), s.client, url.Values{
+		spi.ParamClientId:     {"foo"},
+		spi.ParamResponseType: {spi.ResponseTypeCode},
+	})
+	s.Require().NotNil(err)
+	s.Assert().Equal("invalid_request", err.(*spi.OAuthError).Err)
+}
+
+func (s *PushedAuthorizationTestSuite) formRequest(form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/oauth/par", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", oauth.ContentTypeForm)
+	return r
+}
+
+type parTestClient struct {
+	*panicClient
+	id string
+}
+
+func (c *parTestClient) GetId() string {
+	return c.id
+}
+
+func (c *parTestClient) GetRedirectUris() []string {
+	return []string{"https://foo.com/callback"}
+}
+
+func (c *parTestClient) GetResponseTypes() []string {
+	return []string{spi.ResponseTypeCode}
+}
+
+type parTestAuthentication struct {
+	client spi.OAuthClient
+}
+
+func (a *parTestAuthentication) Authenticate(ctx context.Context, r *http.Request) (spi.OAuthClient, error) {
+	return a.client, nil
+}
+
+func (a *parTestAuthentication) Method() string {
+	return spi.AuthMethodClientSecretPost
+}
+
+func (a *parTestAuthentication) Supports(r *http.Request) bool {
+	return true
+}
+
+type inMemPushedAuthorizationRepo struct {
+	sync.Mutex
+	store map[string]*PushedAuthorization
+}
+
+func (r *inMemPushedAuthorizationRepo) Save(ctx context.Context, requestUri string, par *PushedAuthorization) error {
+	r.Lock()
+	defer r.Unlock()
+	r.store[requestUri] = par
+	return nil
+}
+
+func (r *inMemPushedAuthorizationRepo) Consume(ctx context.Context, requestUri string) (*PushedAuthorization, error) {
+	r.Lock()
+	defer r.Unlock()
+	par := r.store[requestUri]
+	delete(r.store, requestUri)
+	return par, nil
+}
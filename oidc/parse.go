@@ -0,0 +1,92 @@
+package oidc
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Create an oauth.RequestParser which, in addition to the OAuth 2.0 parameters, parses the Open ID Connect authorize
+// parameters defined in Open ID Connect Core 1.0 Section 3.1.2.1. Authorize requests are accepted through both GET and
+// POST, and must be parsed into an oidc.AuthorizeRequest. Token requests are parsed as in OAuth 2.0.
+func NewHttpRequestParser(lookup spi.ClientLookup, authentication oauth.ClientAuthentication,
+	resolvers ...oauth.AuthorizeParameterResolver) oauth.RequestParser {
+	return &httpRequestParser{
+		RequestParser: oauth.NewHttpRequestParser(lookup, authentication, resolvers...),
+		ClientLookup:  lookup,
+		Resolvers:     resolvers,
+	}
+}
+
+type httpRequestParser struct {
+	oauth.RequestParser
+	ClientLookup spi.ClientLookup
+	Resolvers    []oauth.AuthorizeParameterResolver
+}
+
+func (p *httpRequestParser) ParseAuthorizeRequest(ctx context.Context, r *http.Request, req oauth.AuthorizeRequest) error {
+	authReq, ok := req.(AuthorizeRequest)
+	if !ok {
+		return spi.ErrServerErrorf("request must be an oidc.AuthorizeRequest")
+	}
+
+	values, err := oauth.ReadAuthorizeParameters(r)
+	if err != nil {
+		return err
+	}
+
+	values, err = oauth.ParseAuthorizeParameters(ctx, p.ClientLookup, p.Resolvers, values, authReq)
+	if err != nil {
+		return err
+	}
+
+	return parseOidcAuthorizeParameters(values, authReq)
+}
+
+func parseOidcAuthorizeParameters(values url.Values, req AuthorizeRequest) error {
+	req.SetResponseMode(values.Get(spi.ParamResponseMode))
+	req.SetNonce(values.Get(spi.ParamNonce))
+	req.SetDisplay(values.Get(spi.ParamDisplay))
+	req.SetIdTokenHint(values.Get(spi.ParamIdTokenHint))
+	req.SetLoginHint(values.Get(spi.ParamLoginHint))
+	req.SetIss(values.Get(spi.ParamIss))
+	req.SetTargetLinkUri(values.Get(spi.ParamTargetLinkUri))
+
+	if prompts := strings.Fields(values.Get(spi.ParamPrompt)); len(prompts) > 0 {
+		req.AddPrompt(prompts...)
+	}
+
+	if locales := strings.Fields(values.Get(spi.ParamUiLocales)); len(locales) > 0 {
+		req.AddUiLocale(locales...)
+	}
+
+	if acrValues := strings.Fields(values.Get(spi.ParamAcrValues)); len(acrValues) > 0 {
+		req.AddAcrValue(acrValues...)
+	}
+
+	if locales := strings.Fields(values.Get(spi.ParamClaimsLocales)); len(locales) > 0 {
+		req.AddClaimsLocale(locales...)
+	}
+
+	if maxAge := values.Get(spi.ParamMaxAge); len(maxAge) > 0 {
+		if v, err := strconv.ParseUint(maxAge, 10, 64); err != nil {
+			return spi.ErrInvalidRequest("max_age must be a non-negative integer.")
+		} else {
+			req.SetMaxAge(v)
+		}
+	}
+
+	if claims := values.Get(spi.ParamClaims); len(claims) > 0 {
+		if cr, err := ParseClaimsRequest(claims); err != nil {
+			return err
+		} else {
+			req.SetClaims(cr)
+		}
+	}
+
+	return nil
+}
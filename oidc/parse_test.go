@@ -0,0 +1,104 @@
+package oidc
+
+import (
+	"context"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/imulab-z/platform-sdk/test"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHttpRequestParser(t *testing.T) {
+	s := new(HttpRequestParserTestSuite)
+	suite.Run(t, s)
+}
+
+type HttpRequestParserTestSuite struct {
+	suite.Suite
+	parser oauth.RequestParser
+}
+
+func (s *HttpRequestParserTestSuite) SetupTest() {
+	lookup := new(test.MockClientLookup)
+	lookup.On("FindById", "foo").Return(&parTestClient{id: "foo"}, nil)
+	s.parser = NewHttpRequestParser(lookup, nil)
+}
+
+func (s *HttpRequestParserTestSuite) TestParseAuthorizeRequest() {
+	values := url.Values{}
+	values.Set(spi.ParamClientId, "foo")
+	values.Set(spi.ParamResponseType, spi.ResponseTypeCode)
+	values.Set(spi.ParamRedirectUri, "https://foo.com/callback")
+	values.Set(spi.ParamScope, "openid profile")
+	values.Set(spi.ParamState, "12345678")
+	values.Set(spi.ParamResponseMode, spi.ResponseModeFormPost)
+	values.Set(spi.ParamNonce, "abcdefgh")
+	values.Set(spi.ParamDisplay, spi.DisplayPopup)
+	values.Set(spi.ParamPrompt, "login consent")
+	values.Set(spi.ParamMaxAge, "3600")
+	values.Set(spi.ParamUiLocales, "ja-JP en")
+	values.Set(spi.ParamIdTokenHint, "some-id-token")
+	values.Set(spi.ParamLoginHint, "john@test.org")
+	values.Set(spi.ParamAcrValues, "urn:mace:incommon:iap:silver")
+	values.Set(spi.ParamClaims, `{"userinfo":{"email":{"essential":true}}}`)
+	values.Set(spi.ParamClaimsLocales, "ja-Kana-JP")
+	values.Set(spi.ParamIss, "https://third-party.com")
+	values.Set(spi.ParamTargetLinkUri, "https://foo.com/landing")
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+values.Encode(), nil),
+		s.formRequest(values),
+	} {
+		req := NewAuthorizeRequest()
+		s.Require().Nil(s.parser.ParseAuthorizeRequest(context.Background(), r, req), r.Method)
+
+		s.Assert().Equal("foo", req.GetClient().GetId())
+		s.Assert().Equal([]string{spi.ResponseTypeCode}, req.GetResponseTypes())
+		s.Assert().Equal("https://foo.com/callback", req.GetRedirectUri())
+		s.Assert().Equal(spi.ResponseModeFormPost, req.GetResponseMode())
+		s.Assert().Equal("abcdefgh", req.GetNonce())
+		s.Assert().Equal(spi.DisplayPopup, req.GetDisplay())
+		s.Assert().Equal([]string{spi.PromptLogin, spi.PromptConsent}, req.GetPrompts())
+		s.Assert().Equal(uint64(3600), req.GetMaxAge())
+		s.Assert().Equal([]string{"ja-JP", "en"}, req.GetUiLocales())
+		s.Assert().Equal("some-id-token", req.GetIdTokenHint())
+		s.Assert().Equal("john@test.org", req.GetLoginHint())
+		s.Assert().Equal([]string{"urn:mace:incommon:iap:silver"}, req.GetAcrValues())
+		s.Assert().True(req.GetClaims().UserInfo["email"].Essential)
+		s.Assert().Equal([]string{"ja-Kana-JP"}, req.GetClaimsLocales())
+		s.Assert().Equal("https://third-party.com", req.GetIss())
+		s.Assert().Equal("https://foo.com/landing", req.GetTargetLinkUri())
+	}
+}
+
+func (s *HttpRequestParserTestSuite) TestParseInvalidAuthorizeRequest() {
+	for _, v := range []struct {
+		name  string
+		key   string
+		value string
+	}{
+		{name: "negative max_age", key: spi.ParamMaxAge, value: "-1"},
+		{name: "non numeric max_age", key: spi.ParamMaxAge, value: "one hour"},
+		{name: "malformed claims", key: spi.ParamClaims, value: `{"userinfo":`},
+	} {
+		values := url.Values{}
+		values.Set(spi.ParamClientId, "foo")
+		values.Set(spi.ParamResponseType, spi.ResponseTypeCode)
+		values.Set(v.key, v.value)
+
+		err := s.parser.ParseAuthorizeRequest(context.Background(), s.formRequest(values), NewAuthorizeRequest())
+		s.Require().NotNil(err, v.name)
+		s.Assert().Equal("invalid_request", err.(*spi.OAuthError).Err, v.name)
+	}
+}
+
+func (s *HttpRequestParserTestSuite) formRequest(values url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(values.Encode()))
+	r.Header.Set("Content-Type", oauth.ContentTypeForm)
+	return r
+}
@@ -18,13 +18,14 @@ func NewRequest() oauth.Request {
 	}
 }
 
+// Client and OidcSession are interfaces, hence are serialized separately by oauth.RequestCodec.
 type oidcRequest struct {
 	Id          string         `json:"id"`
 	Timestamp   int64          `json:"timestamp"`
-	Client      spi.OidcClient `json:"client"`
+	Client      spi.OidcClient `json:"-"`
 	RedirectUri string         `json:"redirect_uri"`
 	Scopes 		[]string 		`json:"scopes"`
-	OidcSession Session        `json:"session"`
+	OidcSession Session        `json:"-"`
 }
 
 func (r *oidcRequest) GetId() string {
@@ -25,12 +25,19 @@ type AuthorizeRequest interface {
 	// id_token_hint
 	GetIdTokenHint() string
 	SetIdTokenHint(hint string)
+	// login_hint
+	GetLoginHint() string
+	SetLoginHint(hint string)
 	// acr_values
 	GetAcrValues() []string
 	AddAcrValue(values ...string)
 	// claims
-	GetClaims() map[string]interface{}
+	// The claims request is also recorded in the request session, so that it remains in effect when tokens are issued
+	// at the token endpoint.
+	GetClaims() *ClaimsRequest
+	SetClaims(claims *ClaimsRequest)
 	// claims_locales
+	// As with claims, the locales are also recorded in the request session.
 	GetClaimsLocales() []string
 	AddClaimsLocale(locales ...string)
 	// iss
@@ -47,6 +54,8 @@ func NewAuthorizeRequest() AuthorizeRequest {
 		ResponseTypes: make([]string, 0),
 		Scopes:        make([]string, 0),
 		State:         "",
+		CodeChallenge: "",
+		CodeChallengeMethod: "",
 		handleMap:     make(map[string]struct{}),
 		ResponseMode:  "",
 		Nonce:         "",
@@ -55,8 +64,9 @@ func NewAuthorizeRequest() AuthorizeRequest {
 		MaxAge:        0,
 		UiLocales:     make([]string, 0),
 		IdTokenHint:   "",
+		LoginHint:     "",
 		AcrValues:     make([]string, 0),
-		Claims:        make(map[string]interface{}),
+		Claims:        nil,
 		ClaimsLocales: make([]string, 0),
 		Iss:           "",
 		TargetLinkUri: "",
@@ -65,22 +75,25 @@ func NewAuthorizeRequest() AuthorizeRequest {
 
 type authorizeRequest struct {
 	*oidcRequest
-	ResponseTypes []string               `json:"response_types"`
-	Scopes        []string               `json:"scopes"`
-	State         string                 `json:"state"`
-	handleMap     map[string]struct{}    `json:"-"`
-	ResponseMode  string                 `json:"response_mode"`
-	Nonce         string                 `json:"nonce"`
-	Display       string                 `json:"display"`
-	Prompts       []string               `json:"prompts"`
-	MaxAge        uint64                 `json:"max_age"`
-	UiLocales     []string               `json:"ui_locales"`
-	IdTokenHint   string                 `json:"id_token_hint"`
-	AcrValues     []string               `json:"acr_values"`
-	Claims        map[string]interface{} `json:"claims"`
-	ClaimsLocales []string               `json:"claims_locales"`
-	Iss           string                 `json:"iss"`
-	TargetLinkUri string                 `json:"target_link_uri"`
+	ResponseTypes       []string               `json:"response_types"`
+	Scopes              []string               `json:"scopes"`
+	State               string                 `json:"state"`
+	CodeChallenge       string                 `json:"code_challenge"`
+	CodeChallengeMethod string                 `json:"code_challenge_method"`
+	handleMap           map[string]struct{}    `json:"-"`
+	ResponseMode        string                 `json:"response_mode"`
+	Nonce               string                 `json:"nonce"`
+	Display             string                 `json:"display"`
+	Prompts             []string               `json:"prompts"`
+	MaxAge              uint64                 `json:"max_age"`
+	UiLocales           []string               `json:"ui_locales"`
+	IdTokenHint         string                 `json:"id_token_hint"`
+	LoginHint           string                 `json:"login_hint"`
+	AcrValues           []string               `json:"acr_values"`
+	Claims              *ClaimsRequest         `json:"claims"`
+	ClaimsLocales       []string               `json:"claims_locales"`
+	Iss                 string                 `json:"iss"`
+	TargetLinkUri       string                 `json:"target_link_uri"`
 }
 
 func (r *authorizeRequest) GetResponseTypes() []string {
@@ -116,6 +129,22 @@ func (r *authorizeRequest) SetState(state string) {
 	r.State = state
 }
 
+func (r *authorizeRequest) GetCodeChallenge() string {
+	return r.CodeChallenge
+}
+
+func (r *authorizeRequest) SetCodeChallenge(challenge string) {
+	r.CodeChallenge = challenge
+}
+
+func (r *authorizeRequest) GetCodeChallengeMethod() string {
+	return r.CodeChallengeMethod
+}
+
+func (r *authorizeRequest) SetCodeChallengeMethod(method string) {
+	r.CodeChallengeMethod = method
+}
+
 func (r *authorizeRequest) GetResponseMode() string {
 	return r.ResponseMode
 }
@@ -172,6 +201,14 @@ func (r *authorizeRequest) SetIdTokenHint(hint string) {
 	r.IdTokenHint = hint
 }
 
+func (r *authorizeRequest) GetLoginHint() string {
+	return r.LoginHint
+}
+
+func (r *authorizeRequest) SetLoginHint(hint string) {
+	r.LoginHint = hint
+}
+
 func (r *authorizeRequest) GetAcrValues() []string {
 	return r.AcrValues
 }
@@ -180,16 +217,26 @@ func (r *authorizeRequest) AddAcrValue(values ...string) {
 	r.AcrValues = append(r.AcrValues, values...)
 }
 
-func (r *authorizeRequest) GetClaims() map[string]interface{} {
+func (r *authorizeRequest) GetClaims() *ClaimsRequest {
 	return r.Claims
 }
 
+func (r *authorizeRequest) SetClaims(claims *ClaimsRequest) {
+	r.Claims = claims
+	if sess, ok := r.GetSession().(Session); ok {
+		sess.SetClaimsRequest(claims)
+	}
+}
+
 func (r *authorizeRequest) GetClaimsLocales() []string {
 	return r.ClaimsLocales
 }
 
 func (r *authorizeRequest) AddClaimsLocale(locales ...string) {
 	r.ClaimsLocales = append(r.ClaimsLocales, locales...)
+	if sess, ok := r.GetSession().(Session); ok {
+		sess.AddClaimsLocales(locales...)
+	}
 }
 
 func (r *authorizeRequest) GetIss() string {
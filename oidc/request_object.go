@@ -0,0 +1,391 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	_ oauth.AuthorizeParameterResolver = (*RequestObjectResolver)(nil)
+)
+
+const (
+	// Time a request object is cached by CachingRequestUriFetcher by default.
+	DefaultRequestObjectCacheTTL = 10 * time.Minute
+	// Number of request objects cached by CachingRequestUriFetcher by default.
+	DefaultRequestObjectCacheSize = 1000
+	// Clock skew tolerated when checking the exp and nbf claims of a request object.
+	requestObjectLeeway = 5 * time.Second
+)
+
+// Retrieves the request object hosted at a request_uri.
+type RequestUriFetcher interface {
+	Fetch(ctx context.Context, uri string) (string, error)
+}
+
+// RequestUriFetcher which retrieves the request object over HTTP. A client timing out after DefaultFetchTimeout is
+// used if Client is nil. Request objects larger than 64KB are rejected.
+type HttpRequestUriFetcher struct {
+	Client *http.Client
+}
+
+func (f *HttpRequestUriFetcher) Fetch(ctx context.Context, uri string) (string, error) {
+	client := f.Client
+	if client == nil {
+		client = defaultFetchClient
+	}
+
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("request_uri responded with status %d", resp.StatusCode)
+	}
+
+	raw, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxFetchedDocumentSize+1))
+	if err != nil {
+		return "", err
+	} else if len(raw) > maxFetchedDocumentSize {
+		return "", fmt.Errorf("request_uri content exceeds %d bytes", maxFetchedDocumentSize)
+	}
+
+	return strings.TrimSpace(string(raw)), nil
+}
+
+// RequestUriFetcher which caches request objects fetched by the Delegate. As suggested by Open ID Connect Core 1.0
+// Section 6.2, the fragment of a request_uri is taken as the base64url encoded SHA-256 hash of its content: a cached
+// request object is only served while its hash matches the fragment, and fetched again once the client changes the
+// fragment. Request uris without fragment are never cached.
+//
+// Cached request objects expire after TTL (DefaultRequestObjectCacheTTL by default), and at most MaxEntries
+// (DefaultRequestObjectCacheSize by default) are kept, the ones closest to expiry are evicted first.
+type CachingRequestUriFetcher struct {
+	Delegate   RequestUriFetcher
+	TTL        time.Duration
+	MaxEntries int
+
+	mu    sync.RWMutex
+	cache map[string]cachedRequestObject
+}
+
+type cachedRequestObject struct {
+	hash      string
+	content   string
+	expiresAt time.Time
+}
+
+func (f *CachingRequestUriFetcher) Fetch(ctx context.Context, uri string) (string, error) {
+	location, fragment := splitFragment(uri)
+	if len(fragment) == 0 {
+		return f.Delegate.Fetch(ctx, location)
+	}
+
+	f.mu.RLock()
+	cached, ok := f.cache[location]
+	f.mu.RUnlock()
+	if ok && cached.hash == fragment && time.Now().Before(cached.expiresAt) {
+		return cached.content, nil
+	}
+
+	content, err := f.Delegate.Fetch(ctx, location)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(content))
+	hash := base64.RawURLEncoding.EncodeToString(sum[:])
+	if hash != fragment {
+		return "", fmt.Errorf("content of request_uri does not match its hash fragment")
+	}
+
+	f.put(location, cachedRequestObject{hash: hash, content: content})
+
+	return content, nil
+}
+
+func (f *CachingRequestUriFetcher) put(location string, entry cachedRequestObject) {
+	ttl, size := f.TTL, f.MaxEntries
+	if ttl == 0 {
+		ttl = DefaultRequestObjectCacheTTL
+	}
+	if size == 0 {
+		size = DefaultRequestObjectCacheSize
+	}
+
+	now := time.Now()
+	entry.expiresAt = now.Add(ttl)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.cache == nil {
+		f.cache = make(map[string]cachedRequestObject)
+	}
+	delete(f.cache, location)
+	for k, v := range f.cache {
+		if !now.Before(v.expiresAt) {
+			delete(f.cache, k)
+		}
+	}
+	for len(f.cache) >= size {
+		oldest := ""
+		for k, v := range f.cache {
+			if len(oldest) == 0 || v.expiresAt.Before(f.cache[oldest].expiresAt) {
+				oldest = k
+			}
+		}
+		delete(f.cache, oldest)
+	}
+	f.cache[location] = entry
+}
+
+// oauth.AuthorizeParameterResolver which expands request objects passed by value in the request parameter, or by
+// reference in the request_uri parameter (Open ID Connect Core 1.0 Section 6 and RFC 9101).
+//
+// Request objects encrypted to the server are decrypted with the keys in Jwks, then verified against the client's
+// registered json web key set with its request_object_signing_alg (RS256 by default). Unsigned request objects are only
+// accepted when the client registered 'none'. Parameters in the request object take precedence over the ones in the
+// query, and client_id, if present in both, must match.
+//
+// Issuer is the expected audience of the request object. Fetcher retrieves request uris, request_uri is not supported
+// when it is nil. Request uris must be pre-registered by the client, so that the server is not made to fetch arbitrary
+// locations. AllowUnregisteredRequestUri lifts this requirement for clients that did not register any request_uris.
+type RequestObjectResolver struct {
+	Issuer                      string
+	Jwks                        *jose.JSONWebKeySet
+	Fetcher                     RequestUriFetcher
+	AllowUnregisteredRequestUri bool
+}
+
+func (r *RequestObjectResolver) ResolveAuthorizeParameters(ctx context.Context, client spi.OAuthClient, values url.Values) (
+	url.Values, error) {
+	request, requestUri := values.Get(spi.ParamRequest), values.Get(spi.ParamRequestUri)
+
+	switch {
+	case len(request) == 0 && len(requestUri) == 0:
+		return values, nil
+	case len(request) > 0 && len(requestUri) > 0:
+		return nil, spi.ErrInvalidRequest("request and request_uri must not be used together.")
+	}
+
+	oidcClient, ok := client.(spi.OidcClient)
+	if !ok {
+		return nil, spi.ErrServerErrorf("client is not a spi.OidcClient")
+	}
+
+	if len(requestUri) > 0 {
+		var err error
+		if request, err = r.fetch(ctx, requestUri, oidcClient); err != nil {
+			return nil, err
+		}
+	}
+
+	claims, err := r.decode(request, oidcClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.merge(values, claims, oidcClient)
+}
+
+func (r *RequestObjectResolver) fetch(ctx context.Context, requestUri string, client spi.OidcClient) (string, error) {
+	if r.Fetcher == nil {
+		return "", spi.ErrRequestUriNotSupported()
+	}
+
+	if !r.AllowUnregisteredRequestUri || len(client.GetRequestUris()) > 0 {
+		location, _ := splitFragment(requestUri)
+		registered := false
+		for _, uri := range client.GetRequestUris() {
+			if registeredLocation, _ := splitFragment(uri); registeredLocation == location {
+				registered = true
+				break
+			}
+		}
+		if !registered {
+			return "", spi.ErrInvalidRequestUri("request_uri is not registered by client.")
+		}
+	}
+
+	if content, err := r.Fetcher.Fetch(ctx, requestUri); err != nil {
+		return "", spi.ErrInvalidRequestUri(err.Error())
+	} else {
+		return content, nil
+	}
+}
+
+// Decrypt (if necessary) and verify the request object, returns its claims.
+func (r *RequestObjectResolver) decode(raw string, client spi.OidcClient) (map[string]interface{}, error) {
+	if strings.Count(raw, ".") == 4 {
+		var err error
+		if raw, err = r.decrypt(raw, client); err != nil {
+			return nil, err
+		}
+	}
+
+	tok, err := jwt.ParseSigned(raw)
+	if err != nil {
+		return nil, spi.ErrInvalidRequestObject("request object is not a signed JWT.")
+	}
+
+	expectedAlg := client.GetRequestObjectSigningAlg()
+	if len(expectedAlg) == 0 {
+		expectedAlg = spi.SignAlgRS256
+	}
+	for _, header := range tok.Headers {
+		if header.Algorithm != expectedAlg {
+			return nil, spi.ErrInvalidRequestObject("request object signing algorithm mismatch with " +
+				"request_object_signing_alg.")
+		}
+	}
+
+	claims := make(map[string]interface{})
+	if expectedAlg == spi.SignAlgNone {
+		err = tok.UnsafeClaimsWithoutVerification(&claims)
+	} else {
+		jwks := new(jose.JSONWebKeySet)
+		if err := json.NewDecoder(strings.NewReader(client.GetJwks())).Decode(jwks); err != nil {
+			return nil, spi.ErrServerErrorf("invalid client json web key set: %s", err.Error())
+		}
+		err = tok.Claims(jwks, &claims)
+	}
+	if err != nil {
+		return nil, spi.ErrInvalidRequestObject("request object failed verification.")
+	}
+
+	return claims, r.validate(claims, client)
+}
+
+func (r *RequestObjectResolver) decrypt(raw string, client spi.OidcClient) (string, error) {
+	encrypted, err := jose.ParseEncrypted(raw)
+	if err != nil {
+		return "", spi.ErrInvalidRequestObject("request object is not a valid JWE.")
+	}
+
+	alg := encrypted.Header.Algorithm
+	if registered := client.GetRequestObjectEncryptionAlg(); len(registered) > 0 && registered != alg {
+		return "", spi.ErrInvalidRequestObject("request object encryption algorithm mismatch with " +
+			"request_object_encryption_alg.")
+	}
+
+	key := oauth.FindDecryptionKeyByAlg(r.Jwks, alg)
+	if key == nil {
+		return "", spi.ErrInvalidRequestObject("no server key to decrypt request object.")
+	}
+
+	if plain, err := encrypted.Decrypt(key); err != nil {
+		return "", spi.ErrInvalidRequestObject("request object failed decryption.")
+	} else {
+		return string(plain), nil
+	}
+}
+
+// Validates the registered claims when present: iss must be the client, aud must include the server and the request
+// object must not have expired nor be used before its nbf time, allowing for requestObjectLeeway.
+func (r *RequestObjectResolver) validate(claims map[string]interface{}, client spi.OidcClient) error {
+	if iss, ok := claims["iss"]; ok && iss != client.GetId() {
+		return spi.ErrInvalidRequestObject("request object must be issued by client.")
+	}
+
+	if aud, ok := claims["aud"]; ok && len(r.Issuer) > 0 {
+		var audience jwt.Audience
+		if raw, err := json.Marshal(aud); err != nil {
+			return spi.ErrInvalidRequestObject("invalid aud in request object.")
+		} else if err := json.Unmarshal(raw, &audience); err != nil {
+			return spi.ErrInvalidRequestObject("invalid aud in request object.")
+		}
+		if !audience.Contains(r.Issuer) {
+			return spi.ErrInvalidRequestObject("request object is not intended for this server.")
+		}
+	}
+
+	if exp, ok := claims["exp"].(float64); ok && time.Now().Add(-requestObjectLeeway).After(time.Unix(int64(exp), 0)) {
+		return spi.ErrInvalidRequestObject("request object has expired.")
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && time.Now().Add(requestObjectLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return spi.ErrInvalidRequestObject("request object is not yet valid.")
+	}
+
+	return nil
+}
+
+// Registered claims of the request object which are not authorization parameters.
+var requestObjectRegisteredClaims = []string{"iss", "aud", "exp", "iat", "nbf", "jti", "sub"}
+
+// Merge the request object claims over the query parameters. The request and request_uri parameters are dropped.
+func (r *RequestObjectResolver) merge(values url.Values, claims map[string]interface{}, client spi.OidcClient) (
+	url.Values, error) {
+	if clientId, ok := claims[spi.ParamClientId]; ok && clientId != values.Get(spi.ParamClientId) {
+		return nil, spi.ErrInvalidRequestObject("client_id in request object mismatch with request.")
+	}
+
+	merged := url.Values{}
+	for k, v := range values {
+		merged[k] = v
+	}
+	merged.Del(spi.ParamRequest)
+	merged.Del(spi.ParamRequestUri)
+
+	for k, v := range claims {
+		if oauth.V(requestObjectRegisteredClaims).Contains(k) {
+			continue
+		}
+		if s, err := parameterValue(v); err != nil {
+			return nil, spi.ErrInvalidRequestObject(fmt.Sprintf("invalid %s in request object.", k))
+		} else {
+			merged.Set(k, s)
+		}
+	}
+
+	return merged, nil
+}
+
+// Returns the claim value in its parameter form: strings as is, numbers and booleans formatted, and objects (i.e.
+// claims) in JSON.
+func parameterValue(v interface{}) (string, error) {
+	switch v.(type) {
+	case string:
+		return v.(string), nil
+	case float64:
+		return strconv.FormatFloat(v.(float64), 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v.(bool)), nil
+	default:
+		if raw, err := json.Marshal(v); err != nil {
+			return "", err
+		} else {
+			return string(raw), nil
+		}
+	}
+}
+
+func splitFragment(uri string) (string, string) {
+	if i := strings.Index(uri, "#"); i >= 0 {
+		return uri[:i], uri[i+1:]
+	}
+	return uri, ""
+}
@@ -0,0 +1,353 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRequestObjectResolver(t *testing.T) {
+	s := new(RequestObjectResolverTestSuite)
+	suite.Run(t, s)
+}
+
+type RequestObjectResolverTestSuite struct {
+	suite.Suite
+	clientKey *rsa.PrivateKey
+	serverKey *rsa.PrivateKey
+	client    *requestObjectTestClient
+	fetcher   *countingRequestUriFetcher
+	resolver  *RequestObjectResolver
+}
+
+func (s *RequestObjectResolverTestSuite) SetupTest() {
+	var err error
+	s.clientKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().Nil(err)
+	s.serverKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().Nil(err)
+
+	clientJwks, err := json.Marshal(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: s.clientKey.Public(), KeyID: "client", Use: "sig", Algorithm: spi.SignAlgRS256},
+	}})
+	s.Require().Nil(err)
+
+	s.client = &requestObjectTestClient{
+		id:          "foo",
+		jwks:        string(clientJwks),
+		signAlg:     spi.SignAlgRS256,
+		requestUris: []string{"https://client.com/request.jwt"},
+	}
+	s.fetcher = &countingRequestUriFetcher{content: make(map[string]string)}
+	s.resolver = &RequestObjectResolver{
+		Issuer: "https://server.com",
+		Jwks: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: s.serverKey, KeyID: "server-enc", Use: "enc", Algorithm: string(jose.RSA_OAEP)},
+		}},
+		Fetcher: &CachingRequestUriFetcher{Delegate: s.fetcher},
+	}
+}
+
+func (s *RequestObjectResolverTestSuite) TestResolveRequest() {
+	values := url.Values{}
+	values.Set(spi.ParamClientId, "foo")
+	values.Set(spi.ParamResponseType, spi.ResponseTypeCode)
+	values.Set(spi.ParamScope, "foo")
+	values.Set(spi.ParamState, "12345678")
+	values.Set(spi.ParamRequest, s.sign(map[string]interface{}{
+		spi.ParamClientId: "foo",
+		spi.ParamScope:    "openid bar",
+		"max_age":         3600,
+		"claims":          map[string]interface{}{"userinfo": map[string]interface{}{"email": nil}},
+		// slightly ahead of the server clock, within the leeway
+		"nbf": time.Now().Add(time.Second).Unix(),
+	}))
+
+	resolved, err := s.resolver.ResolveAuthorizeParameters(context.Background(), s.client, values)
+	s.Require().Nil(err)
+	s.Assert().Empty(resolved.Get(spi.ParamRequest))
+	s.Assert().Equal("openid bar", resolved.Get(spi.ParamScope))
+	s.Assert().Equal("12345678", resolved.Get(spi.ParamState))
+	s.Assert().Equal("3600", resolved.Get("max_age"))
+	s.Assert().JSONEq(`{"userinfo":{"email":null}}`, resolved.Get("claims"))
+	s.Assert().Empty(resolved.Get("iss"))
+}
+
+func (s *RequestObjectResolverTestSuite) TestResolveEncryptedRequest() {
+	s.client.encryptAlg = string(jose.RSA_OAEP)
+
+	encrypter, err := jose.NewEncrypter(jose.A128CBC_HS256, jose.Recipient{
+		Algorithm: jose.RSA_OAEP,
+		Key:       s.serverKey.Public(),
+	}, nil)
+	s.Require().Nil(err)
+	obj, err := encrypter.Encrypt([]byte(s.sign(map[string]interface{}{spi.ParamState: "87654321"})))
+	s.Require().Nil(err)
+	raw, err := obj.CompactSerialize()
+	s.Require().Nil(err)
+
+	values := url.Values{}
+	values.Set(spi.ParamClientId, "foo")
+	values.Set(spi.ParamRequest, raw)
+
+	resolved, err := s.resolver.ResolveAuthorizeParameters(context.Background(), s.client, values)
+	s.Require().Nil(err)
+	s.Assert().Equal("87654321", resolved.Get(spi.ParamState))
+}
+
+func (s *RequestObjectResolverTestSuite) TestResolveRequestUri() {
+	content := s.sign(map[string]interface{}{spi.ParamState: "12345678"})
+	s.fetcher.content["https://client.com/request.jwt"] = content
+	sum := sha256.Sum256([]byte(content))
+	fragment := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	values := url.Values{}
+	values.Set(spi.ParamClientId, "foo")
+	values.Set(spi.ParamRequestUri, "https://client.com/request.jwt#"+fragment)
+
+	for i := 0; i < 2; i++ {
+		resolved, err := s.resolver.ResolveAuthorizeParameters(context.Background(), s.client, values)
+		s.Require().Nil(err)
+		s.Assert().Equal("12345678", resolved.Get(spi.ParamState))
+		s.Assert().Empty(resolved.Get(spi.ParamRequestUri))
+	}
+	s.Assert().Equal(1, s.fetcher.count)
+
+	// client changed the content, cached version is retired
+	content = s.sign(map[string]interface{}{spi.ParamState: "87654321"})
+	s.fetcher.content["https://client.com/request.jwt"] = content
+	sum = sha256.Sum256([]byte(content))
+	values.Set(spi.ParamRequestUri, "https://client.com/request.jwt#"+base64.RawURLEncoding.EncodeToString(sum[:]))
+
+	resolved, err := s.resolver.ResolveAuthorizeParameters(context.Background(), s.client, values)
+	s.Require().Nil(err)
+	s.Assert().Equal("87654321", resolved.Get(spi.ParamState))
+	s.Assert().Equal(2, s.fetcher.count)
+}
+
+func (s *RequestObjectResolverTestSuite) TestUnregisteredRequestUri() {
+	content := s.sign(map[string]interface{}{spi.ParamState: "12345678"})
+	s.fetcher.content["https://client.com/other.jwt"] = content
+
+	values := url.Values{}
+	values.Set(spi.ParamClientId, "foo")
+	values.Set(spi.ParamRequestUri, "https://client.com/other.jwt")
+
+	s.client.requestUris = nil
+	_, err := s.resolver.ResolveAuthorizeParameters(context.Background(), s.client, values)
+	s.Require().NotNil(err)
+	s.Assert().Equal("invalid_request_uri", err.(*spi.OAuthError).Err)
+	s.Assert().Equal(0, s.fetcher.count)
+
+	s.resolver.AllowUnregisteredRequestUri = true
+	resolved, err := s.resolver.ResolveAuthorizeParameters(context.Background(), s.client, values)
+	s.Require().Nil(err)
+	s.Assert().Equal("12345678", resolved.Get(spi.ParamState))
+}
+
+func (s *RequestObjectResolverTestSuite) TestResolveInvalid() {
+	for _, v := range []struct {
+		name   string
+		values func() url.Values
+		expect string
+	}{
+		{
+			name: "client_id mismatch",
+			values: func() url.Values {
+				return url.Values{
+					spi.ParamClientId: {"foo"},
+					spi.ParamRequest:  {s.sign(map[string]interface{}{spi.ParamClientId: "bar"})},
+				}
+			},
+			expect: "invalid_request_object",
+		},
+		{
+			name: "expired",
+			values: func() url.Values {
+				return url.Values{
+					spi.ParamClientId: {"foo"},
+					spi.ParamRequest:  {s.sign(map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()})},
+				}
+			},
+			expect: "invalid_request_object",
+		},
+		{
+			name: "not yet valid",
+			values: func() url.Values {
+				return url.Values{
+					spi.ParamClientId: {"foo"},
+					spi.ParamRequest:  {s.sign(map[string]interface{}{"nbf": time.Now().Add(time.Minute).Unix()})},
+				}
+			},
+			expect: "invalid_request_object",
+		},
+		{
+			name: "wrong audience",
+			values: func() url.Values {
+				return url.Values{
+					spi.ParamClientId: {"foo"},
+					spi.ParamRequest:  {s.sign(map[string]interface{}{"aud": "https://other.com"})},
+				}
+			},
+			expect: "invalid_request_object",
+		},
+		{
+			name: "unsigned",
+			values: func() url.Values {
+				return url.Values{
+					spi.ParamClientId: {"foo"},
+					spi.ParamRequest:  {"eyJhbGciOiJub25lIn0.eyJzdGF0ZSI6IjEyMzQ1Njc4In0."},
+				}
+			},
+			expect: "invalid_request_object",
+		},
+		{
+			name: "unregistered request_uri",
+			values: func() url.Values {
+				return url.Values{
+					spi.ParamClientId:   {"foo"},
+					spi.ParamRequestUri: {"https://evil.com/request.jwt"},
+				}
+			},
+			expect: "invalid_request_uri",
+		},
+		{
+			name: "both request and request_uri",
+			values: func() url.Values {
+				return url.Values{
+					spi.ParamClientId:   {"foo"},
+					spi.ParamRequest:    {s.sign(map[string]interface{}{})},
+					spi.ParamRequestUri: {"https://client.com/request.jwt"},
+				}
+			},
+			expect: "invalid_request",
+		},
+	} {
+		_, err := s.resolver.ResolveAuthorizeParameters(context.Background(), s.client, v.values())
+		s.Require().NotNil(err, v.name)
+		s.Assert().Equal(v.expect, err.(*spi.OAuthError).Err, v.name)
+	}
+}
+
+func (s *RequestObjectResolverTestSuite) TestCacheBounds() {
+	fetcher := &CachingRequestUriFetcher{Delegate: s.fetcher, TTL: 50 * time.Millisecond, MaxEntries: 2}
+
+	uris := make([]string, 0)
+	for _, location := range []string{"https://client.com/a.jwt", "https://client.com/b.jwt", "https://client.com/c.jwt"} {
+		content := s.sign(map[string]interface{}{"loc": location})
+		s.fetcher.content[location] = content
+		sum := sha256.Sum256([]byte(content))
+		uris = append(uris, location+"#"+base64.RawURLEncoding.EncodeToString(sum[:]))
+	}
+
+	for _, uri := range uris {
+		_, err := fetcher.Fetch(context.Background(), uri)
+		s.Require().Nil(err)
+	}
+	s.Assert().Len(fetcher.cache, 2)
+	s.Assert().Equal(3, s.fetcher.count)
+
+	// first entry was evicted to make room
+	_, err := fetcher.Fetch(context.Background(), uris[0])
+	s.Require().Nil(err)
+	s.Assert().Equal(4, s.fetcher.count)
+
+	// cached entries expire
+	time.Sleep(100 * time.Millisecond)
+	_, err = fetcher.Fetch(context.Background(), uris[0])
+	s.Require().Nil(err)
+	s.Assert().Equal(5, s.fetcher.count)
+}
+
+func TestHttpRequestUriFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large.jwt" {
+			_, _ = w.Write(make([]byte, maxFetchedDocumentSize+1))
+			return
+		}
+		_, _ = w.Write([]byte("header.payload.signature\n"))
+	}))
+	defer server.Close()
+
+	fetcher := &HttpRequestUriFetcher{Client: server.Client()}
+
+	content, err := fetcher.Fetch(context.Background(), server.URL+"/request.jwt")
+	assert.Nil(t, err)
+	assert.Equal(t, "header.payload.signature", content)
+
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/large.jwt")
+	assert.NotNil(t, err)
+}
+
+func (s *RequestObjectResolverTestSuite) sign(claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.RS256,
+		Key:       jose.JSONWebKey{Key: s.clientKey, KeyID: "client"},
+	}, (&jose.SignerOptions{}).WithType("JWT"))
+	s.Require().Nil(err)
+
+	claims["iss"] = "foo"
+	if _, ok := claims["aud"]; !ok {
+		claims["aud"] = "https://server.com"
+	}
+
+	raw, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	s.Require().Nil(err)
+	return raw
+}
+
+type requestObjectTestClient struct {
+	*panicClient
+	id          string
+	jwks        string
+	signAlg     string
+	encryptAlg  string
+	requestUris []string
+}
+
+func (c *requestObjectTestClient) GetId() string {
+	return c.id
+}
+
+func (c *requestObjectTestClient) GetJwks() string {
+	return c.jwks
+}
+
+func (c *requestObjectTestClient) GetRequestObjectSigningAlg() string {
+	return c.signAlg
+}
+
+func (c *requestObjectTestClient) GetRequestObjectEncryptionAlg() string {
+	return c.encryptAlg
+}
+
+func (c *requestObjectTestClient) GetRequestUris() []string {
+	return c.requestUris
+}
+
+type countingRequestUriFetcher struct {
+	content map[string]string
+	count   int
+}
+
+func (f *countingRequestUriFetcher) Fetch(ctx context.Context, uri string) (string, error) {
+	f.count++
+	if content, ok := f.content[uri]; ok {
+		return content, nil
+	}
+	return "", errors.New("not found")
+}
@@ -11,6 +11,9 @@ func NewTokenRequest() TokenRequest {
 		oidcRequest: NewRequest().(*oidcRequest),
 		Code: "",
 		RefreshToken: "",
+		CodeVerifier: "",
+		Username: "",
+		Password: "",
 		GrantTypes: make([]string, 0),
 	}
 }
@@ -18,8 +21,13 @@ func NewTokenRequest() TokenRequest {
 type tokenRequest struct {
 	*oidcRequest
 	GrantTypes		[]string 	`json:"grant_types"`
-	Code 			string		`json:"code"`
-	RefreshToken	string		`json:"refresh_token"`
+	// code and refresh token are never persisted along with the request, repositories store their identifiers instead
+	Code 			string		`json:"-"`
+	RefreshToken	string		`json:"-"`
+	CodeVerifier	string		`json:"code_verifier"`
+	Username		string		`json:"username"`
+	// password is never persisted along with the request
+	Password		string		`json:"-"`
 }
 
 func (r *tokenRequest) AddGrantTypes(grantTypes ...string) {
@@ -46,3 +54,26 @@ func (r *tokenRequest) SetRefreshToken(token string) {
 	r.RefreshToken = token
 }
 
+func (r *tokenRequest) GetCodeVerifier() string {
+	return r.CodeVerifier
+}
+
+func (r *tokenRequest) SetCodeVerifier(verifier string) {
+	r.CodeVerifier = verifier
+}
+
+func (r *tokenRequest) GetUsername() string {
+	return r.Username
+}
+
+func (r *tokenRequest) SetUsername(username string) {
+	r.Username = username
+}
+
+func (r *tokenRequest) GetPassword() string {
+	return r.Password
+}
+
+func (r *tokenRequest) SetPassword(password string) {
+	r.Password = password
+}
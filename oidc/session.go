@@ -16,6 +16,10 @@ type Session interface {
 	GetNonce() string
 	SetNonce(nonce string)
 	GetIdTokenClaims() map[string]interface{}
+	GetClaimsRequest() *ClaimsRequest
+	SetClaimsRequest(claims *ClaimsRequest)
+	GetClaimsLocales() []string
+	AddClaimsLocales(locales ...string)
 }
 
 func NewSession() Session {
@@ -29,6 +33,8 @@ func NewSession() Session {
 		LastReqId: "",
 		AcrValues: make([]string, 0),
 		IdTokenClaims: make(map[string]interface{}),
+		ClaimsRequest: nil,
+		ClaimsLocales: make([]string, 0),
 	}
 }
 
@@ -41,7 +47,9 @@ type oidcSession struct {
 	Nonce			string					`json:"nonce"`
 	AcrValues		[]string				`json:"acr_values"`
 	IdTokenClaims	map[string]interface{}	`json:"id_token_claims"`
-	LastReqId		string					`json:"-"`
+	ClaimsRequest	*ClaimsRequest			`json:"claims_request"`
+	ClaimsLocales	[]string				`json:"claims_locales"`
+	LastReqId		string					`json:"last_request_id"`
 }
 
 func (s *oidcSession) GetLastRequestId() string {
@@ -69,6 +77,9 @@ func (s *oidcSession) Clone() oauth.Session {
 		idTokenClaimsCopy[k] = v
 	}
 
+	claimsLocalesCopy := make([]string, len(s.ClaimsLocales))
+	copy(claimsLocalesCopy, s.ClaimsLocales)
+
 	return &oidcSession{
 		Subject: s.Subject,
 		Scopes: grantedScopesCopy,
@@ -78,6 +89,8 @@ func (s *oidcSession) Clone() oauth.Session {
 		Nonce: s.Nonce,
 		AcrValues: acrValuesCopy,
 		IdTokenClaims: idTokenClaimsCopy,
+		ClaimsRequest: s.ClaimsRequest,
+		ClaimsLocales: claimsLocalesCopy,
 	}
 }
 
@@ -137,6 +150,22 @@ func (s *oidcSession) GetIdTokenClaims() map[string]interface{} {
 	return s.IdTokenClaims
 }
 
+func (s *oidcSession) GetClaimsRequest() *ClaimsRequest {
+	return s.ClaimsRequest
+}
+
+func (s *oidcSession) SetClaimsRequest(claims *ClaimsRequest) {
+	s.ClaimsRequest = claims
+}
+
+func (s *oidcSession) GetClaimsLocales() []string {
+	return s.ClaimsLocales
+}
+
+func (s *oidcSession) AddClaimsLocales(locales ...string) {
+	s.ClaimsLocales = append(s.ClaimsLocales, locales...)
+}
+
 func (s *oidcSession) Merge(another oauth.Session) {
 	if len(s.Subject) == 0 {
 		s.Subject = another.GetSubject()
@@ -166,6 +195,14 @@ func (s *oidcSession) Merge(another oauth.Session) {
 		for k, v := range another.GetIdTokenClaims() {
 			s.GetIdTokenClaims()[k] = v
 		}
+
+		if s.ClaimsRequest == nil {
+			s.ClaimsRequest = another.GetClaimsRequest()
+		}
+
+		if len(s.ClaimsLocales) == 0 {
+			s.AddClaimsLocales(another.GetClaimsLocales()...)
+		}
 	}
 }
 
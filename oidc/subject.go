@@ -0,0 +1,214 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// Timeout of the http client used to retrieve client hosted documents when none is configured.
+	DefaultFetchTimeout = 10 * time.Second
+	// Time a retrieved sector_identifier_uri document is cached by CachingSectorIdentifierFetcher by default.
+	DefaultSectorIdentifierCacheTTL = time.Hour
+	// Maximum size in bytes of a client hosted document.
+	maxFetchedDocumentSize = 64 << 10
+)
+
+// Http client used to retrieve client hosted documents when none is configured.
+var defaultFetchClient = &http.Client{Timeout: DefaultFetchTimeout}
+
+// Fetches the JSON array of redirect uris hosted at a client's sector_identifier_uri.
+type SectorIdentifierFetcher interface {
+	FetchRedirectUris(ctx context.Context, uri string) ([]string, error)
+}
+
+// SectorIdentifierFetcher which retrieves the document over HTTP. A client timing out after DefaultFetchTimeout is
+// used if Client is nil. Documents larger than 64KB are rejected.
+type HttpSectorIdentifierFetcher struct {
+	Client *http.Client
+}
+
+func (f *HttpSectorIdentifierFetcher) FetchRedirectUris(ctx context.Context, uri string) ([]string, error) {
+	client := f.Client
+	if client == nil {
+		client = defaultFetchClient
+	}
+
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sector_identifier_uri responded with status %d", resp.StatusCode)
+	}
+
+	uris := make([]string, 0)
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxFetchedDocumentSize)).Decode(&uris); err != nil {
+		return nil, err
+	}
+
+	return uris, nil
+}
+
+// SectorIdentifierFetcher which caches the redirect uris retrieved by the Delegate for TTL, so that the
+// sector_identifier_uri is not retrieved every time a pairwise subject is computed. TTL defaults to
+// DefaultSectorIdentifierCacheTTL. Expired entries are evicted when a new document is cached.
+type CachingSectorIdentifierFetcher struct {
+	Delegate SectorIdentifierFetcher
+	TTL      time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]cachedRedirectUris
+}
+
+type cachedRedirectUris struct {
+	uris      []string
+	expiresAt time.Time
+}
+
+func (f *CachingSectorIdentifierFetcher) FetchRedirectUris(ctx context.Context, uri string) ([]string, error) {
+	f.mu.RLock()
+	cached, ok := f.cache[uri]
+	f.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.uris, nil
+	}
+
+	uris, err := f.Delegate.FetchRedirectUris(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := f.TTL
+	if ttl == 0 {
+		ttl = DefaultSectorIdentifierCacheTTL
+	}
+
+	now := time.Now()
+	f.mu.Lock()
+	if f.cache == nil {
+		f.cache = make(map[string]cachedRedirectUris)
+	}
+	for k, v := range f.cache {
+		if !now.Before(v.expiresAt) {
+			delete(f.cache, k)
+		}
+	}
+	f.cache[uri] = cachedRedirectUris{uris: uris, expiresAt: now.Add(ttl)}
+	f.mu.Unlock()
+
+	return uris, nil
+}
+
+// Computes the subject identifier presented to a client, as specified in Open ID Connect Core 1.0 Section 8.
+//
+// Clients registered with the pairwise subject type receive the base64url encoded SHA-256 hash of the sector host,
+// the local subject and Salt, so that different sectors cannot correlate the end-user. Other clients receive the local
+// subject as is.
+//
+// The sector host is the host of sector_identifier_uri, whose document (retrieved by Fetcher) must list all redirect
+// uris registered by the client. Wrap Fetcher in a CachingSectorIdentifierFetcher to avoid retrieving the document
+// every time a subject is computed. Without sector_identifier_uri, all registered redirect uris must share a single host,
+// which is then used as the sector host.
+type SubjectObfuscator struct {
+	Salt    []byte
+	Fetcher SectorIdentifierFetcher
+}
+
+func (o *SubjectObfuscator) Obfuscate(ctx context.Context, subject string, client spi.OidcClient) (string, error) {
+	if client.GetSubjectType() != SubjectTypePairwise {
+		return subject, nil
+	}
+
+	sector, err := o.SectorHost(ctx, client)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(sector))
+	h.Write([]byte(subject))
+	h.Write(o.Salt)
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// Returns the sector host of the client.
+func (o *SubjectObfuscator) SectorHost(ctx context.Context, client spi.OidcClient) (string, error) {
+	if sectorUri := client.GetSectorIdentifierUri(); len(sectorUri) > 0 {
+		return o.sectorHostFromDocument(ctx, sectorUri, client)
+	}
+
+	hosts := make(map[string]struct{})
+	for _, redirectUri := range client.GetRedirectUris() {
+		u, err := url.Parse(redirectUri)
+		if err != nil {
+			return "", spi.ErrServerErrorf("client registered malformed redirect_uri %s", redirectUri)
+		}
+		hosts[u.Hostname()] = struct{}{}
+	}
+
+	if len(hosts) != 1 {
+		return "", spi.ErrServerErrorf("client must register sector_identifier_uri to use pairwise subject with " +
+			"redirect_uris of multiple hosts")
+	}
+
+	for host := range hosts {
+		return host, nil
+	}
+	return "", nil
+}
+
+func (o *SubjectObfuscator) sectorHostFromDocument(ctx context.Context, sectorUri string, client spi.OidcClient) (string, error) {
+	u, err := url.Parse(sectorUri)
+	if err != nil || u.Scheme != "https" || len(u.Hostname()) == 0 {
+		return "", spi.ErrServerErrorf("client registered invalid sector_identifier_uri %s", sectorUri)
+	}
+
+	if o.Fetcher == nil {
+		return "", spi.ErrServerErrorf("no fetcher configured to retrieve sector_identifier_uri")
+	}
+
+	listed, err := o.Fetcher.FetchRedirectUris(ctx, sectorUri)
+	if err != nil {
+		return "", spi.ErrServerErrorf("failed to retrieve sector_identifier_uri: %s", err.Error())
+	}
+
+	if !oauth.V(listed).Contains(client.GetRedirectUris()...) {
+		return "", spi.ErrServerErrorf("sector_identifier_uri does not list all redirect_uris of client")
+	}
+
+	return u.Hostname(), nil
+}
+
+// Set the obfuscated subject on the session for the client. No-op when obfuscator is nil.
+func obfuscateSessionSubject(ctx context.Context, obfuscator *SubjectObfuscator, client spi.OidcClient, session Session) error {
+	if obfuscator == nil {
+		return nil
+	}
+
+	if subject, err := obfuscator.Obfuscate(ctx, session.GetSubject(), client); err != nil {
+		return err
+	} else {
+		session.SetObfuscatedSubject(subject)
+	}
+
+	return nil
+}
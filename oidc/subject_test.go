@@ -0,0 +1,179 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSubjectObfuscator_Obfuscate(t *testing.T) {
+	pairwise := func(sector string) string {
+		sum := sha256.Sum256([]byte(sector + "alice" + "salt"))
+		return base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+
+	obfuscator := &SubjectObfuscator{
+		Salt: []byte("salt"),
+		Fetcher: &staticSectorIdentifierFetcher{
+			"https://sector.com/uris.json": {"https://a.com/cb", "https://b.com/cb"},
+		},
+	}
+
+	for _, v := range []struct {
+		name        string
+		client      *subjectTestClient
+		expect      string
+		expectError bool
+	}{
+		{
+			name:   "public client",
+			client: &subjectTestClient{subjectType: SubjectTypePublic, redirectUris: []string{"https://a.com/cb"}},
+			expect: "alice",
+		},
+		{
+			name:   "pairwise client with single host",
+			client: &subjectTestClient{subjectType: SubjectTypePairwise, redirectUris: []string{"https://a.com/cb", "https://a.com/cb2"}},
+			expect: pairwise("a.com"),
+		},
+		{
+			name:        "pairwise client with multiple hosts",
+			client:      &subjectTestClient{subjectType: SubjectTypePairwise, redirectUris: []string{"https://a.com/cb", "https://b.com/cb"}},
+			expectError: true,
+		},
+		{
+			name: "pairwise client with sector_identifier_uri",
+			client: &subjectTestClient{
+				subjectType:  SubjectTypePairwise,
+				sectorUri:    "https://sector.com/uris.json",
+				redirectUris: []string{"https://a.com/cb", "https://b.com/cb"},
+			},
+			expect: pairwise("sector.com"),
+		},
+		{
+			name: "pairwise client with redirect_uri not listed by sector_identifier_uri",
+			client: &subjectTestClient{
+				subjectType:  SubjectTypePairwise,
+				sectorUri:    "https://sector.com/uris.json",
+				redirectUris: []string{"https://c.com/cb"},
+			},
+			expectError: true,
+		},
+		{
+			name: "pairwise client with unreachable sector_identifier_uri",
+			client: &subjectTestClient{
+				subjectType:  SubjectTypePairwise,
+				sectorUri:    "https://unknown.com/uris.json",
+				redirectUris: []string{"https://a.com/cb"},
+			},
+			expectError: true,
+		},
+	} {
+		subject, err := obfuscator.Obfuscate(context.Background(), "alice", v.client)
+		if v.expectError {
+			assert.NotNil(t, err, v.name)
+		} else {
+			assert.Nil(t, err, v.name)
+			assert.Equal(t, v.expect, subject, v.name)
+		}
+	}
+}
+
+func TestHttpSectorIdentifierFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`["https://a.com/cb","https://b.com/cb"]`))
+	}))
+	defer server.Close()
+
+	uris, err := (&HttpSectorIdentifierFetcher{Client: server.Client()}).FetchRedirectUris(context.Background(), server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"https://a.com/cb", "https://b.com/cb"}, uris)
+}
+
+func TestCachingSectorIdentifierFetcher(t *testing.T) {
+	delegate := &countingSectorIdentifierFetcher{uris: []string{"https://a.com/cb"}}
+	fetcher := &CachingSectorIdentifierFetcher{Delegate: delegate, TTL: 50 * time.Millisecond}
+
+	for i := 0; i < 3; i++ {
+		uris, err := fetcher.FetchRedirectUris(context.Background(), "https://sector.com/uris.json")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"https://a.com/cb"}, uris)
+	}
+	assert.Equal(t, 1, delegate.count)
+
+	time.Sleep(100 * time.Millisecond)
+	_, err := fetcher.FetchRedirectUris(context.Background(), "https://sector.com/uris.json")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, delegate.count)
+}
+
+func TestIdTokenHelper_PairwiseSubject(t *testing.T) {
+	req := NewAuthorizeRequest()
+	req.SetClient(&subjectTestClient{subjectType: SubjectTypePairwise, redirectUris: []string{"https://a.com/cb"}})
+	req.SetSession(NewSession())
+	req.GetSession().SetSubject("alice")
+
+	helper := &IdTokenHelper{
+		Strategy:          new(subjectRecordingStrategy),
+		SubjectObfuscator: &SubjectObfuscator{Salt: []byte("salt")},
+	}
+	assert.Nil(t, helper.GenToken(context.Background(), req, oauth.NewResponse()))
+
+	sum := sha256.Sum256([]byte("a.com" + "alice" + "salt"))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(sum[:]), req.GetSession().(Session).GetObfuscatedSubject())
+}
+
+type subjectTestClient struct {
+	*panicClient
+	subjectType  string
+	sectorUri    string
+	redirectUris []string
+}
+
+func (c *subjectTestClient) GetSubjectType() string {
+	return c.subjectType
+}
+
+func (c *subjectTestClient) GetSectorIdentifierUri() string {
+	return c.sectorUri
+}
+
+func (c *subjectTestClient) GetRedirectUris() []string {
+	return c.redirectUris
+}
+
+func (c *subjectTestClient) GetIdTokenSignedResponseAlg() string {
+	return "none"
+}
+
+type staticSectorIdentifierFetcher map[string][]string
+
+func (f staticSectorIdentifierFetcher) FetchRedirectUris(ctx context.Context, uri string) ([]string, error) {
+	if uris, ok := f[uri]; ok {
+		return uris, nil
+	}
+	return nil, errors.New("not found")
+}
+
+type countingSectorIdentifierFetcher struct {
+	uris  []string
+	count int
+}
+
+func (f *countingSectorIdentifierFetcher) FetchRedirectUris(ctx context.Context, uri string) ([]string, error) {
+	f.count++
+	return f.uris, nil
+}
+
+type subjectRecordingStrategy struct{}
+
+func (s *subjectRecordingStrategy) NewToken(ctx context.Context, req oauth.Request) (string, error) {
+	return subjectOf(req.GetSession()), nil
+}
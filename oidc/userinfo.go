@@ -0,0 +1,218 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"net/http"
+	"strings"
+)
+
+const (
+	ContentTypeJwt  = "application/jwt"
+	ContentTypeJson = "application/json;charset=UTF-8"
+)
+
+// Claims released by each of the standard scopes, as defined in Open ID Connect Core 1.0 Section 5.4. The 'sub' claim
+// is always released and hence not listed.
+var ScopeClaims = map[string][]string{
+	spi.ScopeProfile: {
+		"name", "family_name", "given_name", "middle_name", "nickname", "preferred_username", "profile",
+		"picture", "website", "gender", "birthdate", "zoneinfo", "locale", "updated_at",
+	},
+	spi.ScopeEmail:   {"email", "email_verified"},
+	spi.ScopeAddress: {"address"},
+	spi.ScopePhone:   {"phone_number", "phone_number_verified"},
+}
+
+// UserInfo endpoint handler. The bearer access token is validated against the AccessTokenRepo and
+// AccessTokenStrategy, and claims of the end-user are loaded through the ClaimsProvider and filtered by the granted
+// scopes, along with those requested in the userinfo member of the claims request parameter. Language tagged claims
+// (i.e. name#ja-Kana-JP) are selected according to the requested claims_locales. Depending on the client's
+// registration, claims are returned as plain JSON, a signed JWT, or a signed then encrypted JWT. Issuer, Jwks and
+// KeySource are only used when signing is requested, keys are looked up as in JwxIdTokenStrategy. When SubjectObfuscator is set, the 'sub' claim is computed for the client so
+// that it matches the one in id_token.
+type UserInfoHandler struct {
+	AccessTokenStrategy oauth.AccessTokenStrategy
+	AccessTokenRepo     oauth.AccessTokenRepository
+	ClaimsProvider      spi.ClaimsProvider
+	SubjectObfuscator   *SubjectObfuscator
+	Issuer              string
+	Jwks                *jose.JSONWebKeySet
+	KeySource           oauth.SigningKeySource
+}
+
+func (h *UserInfoHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, err := h.bearerToken(r)
+	if err != nil {
+		_ = oauth.WriteTokenError(w, err)
+		return
+	}
+
+	req, claims, err := h.GetUserInfo(r.Context(), token)
+	if err != nil {
+		_ = oauth.WriteTokenError(w, err)
+		return
+	}
+
+	contentType, body, err := h.render(req, claims)
+	if err != nil {
+		_ = oauth.WriteTokenError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// Returns the request the access token was issued for and the claims it is entitled to. Returns an invalid_token error
+// if the access token cannot be found or fails validation, an insufficient_scope error if openid scope was not
+// granted, and a server_error if the repository failed to look up the access token.
+func (h *UserInfoHandler) GetUserInfo(ctx context.Context, token string) (oauth.Request, map[string]interface{}, error) {
+	req, err := h.AccessTokenRepo.GetRequest(ctx, token)
+	switch {
+	case err != nil && oauth.IsStorageFailure(err):
+		return nil, nil, spi.AsOAuthError(err)
+	case err != nil || req == nil:
+		return nil, nil, spi.ErrInvalidToken("access token is not active.")
+	}
+
+	if err := h.AccessTokenStrategy.ValidateToken(ctx, token, req); err != nil {
+		return nil, nil, spi.ErrInvalidToken("access token failed to pass verification.")
+	}
+
+	if !oauth.V(req.GetSession().GetGrantedScopes()).Contains(spi.ScopeOpenId) {
+		return nil, nil, spi.ErrInsufficientScope("openid scope was not granted.", spi.ScopeOpenId)
+	}
+
+	all, err := h.ClaimsProvider.GetClaims(ctx, req.GetSession().GetSubject())
+	if err != nil {
+		return nil, nil, spi.AsOAuthError(err)
+	}
+
+	// the stored session is shared with other readers of the repository, hence not updated in place.
+	sub := subjectOf(req.GetSession())
+	if client, ok := req.GetClient().(spi.OidcClient); ok && h.SubjectObfuscator != nil {
+		if sub, err = h.SubjectObfuscator.Obfuscate(ctx, req.GetSession().GetSubject(), client); err != nil {
+			return nil, nil, spi.AsOAuthError(err)
+		}
+	}
+
+	var (
+		claimsRequest *ClaimsRequest
+		locales       []string
+	)
+	if session, ok := req.GetSession().(Session); ok {
+		claimsRequest, locales = session.GetClaimsRequest(), session.GetClaimsLocales()
+	}
+
+	claims := filterClaimsByScopes(all, req.GetSession().GetGrantedScopes(), locales)
+	if claimsRequest != nil {
+		for k, v := range selectRequestedClaims(all, claimsRequest.UserInfo, locales) {
+			claims[k] = v
+		}
+	}
+	claims["sub"] = sub
+
+	return req, claims, nil
+}
+
+// Extracts the bearer access token from the Authorization header, or from the form encoded body as specified in
+// RFC 6750 Section 2.2.
+func (h *UserInfoHandler) bearerToken(r *http.Request) (string, error) {
+	if header := r.Header.Get(oauth.AuthorizationHeader); len(header) > 0 {
+		parts := strings.SplitN(header, oauth.Space, 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || len(parts[1]) == 0 {
+			return "", spi.ErrInvalidToken("malformed bearer authorization header.")
+		}
+		return parts[1], nil
+	}
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err == nil && len(r.PostForm.Get(oauth.AccessToken)) > 0 {
+			return r.PostForm.Get(oauth.AccessToken), nil
+		}
+	}
+
+	return "", spi.ErrInvalidToken("access token is required.")
+}
+
+// Returns the content type and body of the response according to the client's userinfo_signed_response_alg,
+// userinfo_encrypted_response_alg and userinfo_encrypted_response_enc registration.
+func (h *UserInfoHandler) render(req oauth.Request, claims map[string]interface{}) (string, []byte, error) {
+	client, ok := req.GetClient().(spi.OidcClient)
+	if !ok {
+		return h.renderJson(claims)
+	}
+
+	signAlg, encryptAlg := client.GetUserInfoSignedResponseAlg(), client.GetUserInfoEncryptedResponseAlg()
+	signed := len(signAlg) > 0 && signAlg != spi.SignAlgNone
+	encrypted := len(encryptAlg) > 0 && encryptAlg != spi.EncryptAlgNone
+
+	if !signed && !encrypted {
+		return h.renderJson(claims)
+	}
+
+	var (
+		raw string
+		err error
+	)
+	if signed {
+		raw, err = signClaims(h.KeySource, h.Jwks, signAlg, []interface{}{
+			&jwt.Claims{Issuer: h.Issuer, Audience: []string{client.GetId()}},
+			claims,
+		}, "userinfo")
+	} else {
+		raw, err = mergeClaims([]interface{}{claims})
+	}
+	if err != nil {
+		return "", nil, spi.AsOAuthError(err)
+	}
+
+	if encrypted {
+		enc := client.GetUserInfoEncryptedResponseEnc()
+		if len(enc) == 0 || enc == spi.EncAlgNone {
+			enc = spi.EncAlgA128CBCHS256
+		}
+		if raw, err = encryptForClient(raw, client, encryptAlg, enc, "userinfo"); err != nil {
+			return "", nil, spi.AsOAuthError(err)
+		}
+	}
+
+	return ContentTypeJwt, []byte(raw), nil
+}
+
+func (h *UserInfoHandler) renderJson(claims map[string]interface{}) (string, []byte, error) {
+	if raw, err := json.Marshal(claims); err != nil {
+		return "", nil, spi.ErrServerError(err)
+	} else {
+		return ContentTypeJson, raw, nil
+	}
+}
+
+// Returns claims released by the granted scopes, in the first available of the preferred locales.
+func filterClaimsByScopes(claims map[string]interface{}, scopes []string, locales []string) map[string]interface{} {
+	filtered := make(map[string]interface{})
+	for _, scope := range scopes {
+		for _, name := range ScopeClaims[scope] {
+			if key, v, ok := lookupClaim(claims, name, locales); ok {
+				filtered[key] = v
+			}
+		}
+	}
+	return filtered
+}
+
+// Returns the subject identifier presented to the client, which must be identical to the 'sub' claim in id_token.
+func subjectOf(session oauth.Session) string {
+	if oidcSession, ok := session.(Session); ok && len(oidcSession.GetObfuscatedSubject()) > 0 {
+		return oidcSession.GetObfuscatedSubject()
+	}
+	return session.GetSubject()
+}
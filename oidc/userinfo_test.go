@@ -0,0 +1,334 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/imulab-z/platform-sdk/oauth"
+	"github.com/imulab-z/platform-sdk/spi"
+	"github.com/imulab-z/platform-sdk/test"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUserInfoHandler(t *testing.T) {
+	s := new(UserInfoHandlerTestSuite)
+	suite.Run(t, s)
+}
+
+type UserInfoHandlerTestSuite struct {
+	suite.Suite
+	serverKey    *rsa.PrivateKey
+	clientKey    *rsa.PrivateKey
+	accessHelper *oauth.AccessTokenHelper
+	handler      *UserInfoHandler
+}
+
+func (s *UserInfoHandlerTestSuite) SetupTest() {
+	var err error
+
+	s.serverKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().Nil(err)
+	s.clientKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().Nil(err)
+
+	kid := "5B1A0D8E-6F0C-4E4F-9C2E-0C7D1F3A2B91"
+	repo := &userInfoHandlerTestSuiteAccessTokenRepo{store: make(map[string]oauth.Request)}
+	strategy := oauth.NewRs256JwtAccessTokenStrategy("test", 30*time.Minute, oauth.MustNewJwksWithRsaKeyForSigning(kid), kid)
+
+	s.accessHelper = &oauth.AccessTokenHelper{Strategy: strategy, Repo: repo, Lifespan: 30 * time.Minute, Mode: oauth.PersistSync}
+	s.handler = &UserInfoHandler{
+		AccessTokenStrategy: strategy,
+		AccessTokenRepo:     repo,
+		ClaimsProvider:      new(userInfoHandlerTestSuiteClaimsProvider),
+		Issuer:              "test",
+		Jwks: &jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{
+				{Key: s.serverKey, Algorithm: string(jose.RS256), Use: "sig", KeyID: "test-key"},
+			},
+		},
+	}
+}
+
+func (s *UserInfoHandlerTestSuite) TestPlainJson() {
+	token := s.issue(&userInfoHandlerTestSuiteClient{}, spi.ScopeOpenId, spi.ScopeProfile)
+
+	w := s.serve(token)
+	s.Assert().Equal(http.StatusOK, w.Code)
+	s.Assert().Equal(ContentTypeJson, w.Header().Get("Content-Type"))
+
+	body := make(map[string]interface{})
+	s.Require().Nil(json.NewDecoder(w.Body).Decode(&body))
+	s.Assert().Equal("pairwise user", body["sub"])
+	s.Assert().Equal("John Doe", body["name"])
+	s.Assert().Nil(body["email"])
+}
+
+func (s *UserInfoHandlerTestSuite) TestSignedJwt() {
+	token := s.issue(&userInfoHandlerTestSuiteClient{sign: true}, spi.ScopeOpenId, spi.ScopeEmail)
+
+	w := s.serve(token)
+	s.Assert().Equal(http.StatusOK, w.Code)
+	s.Assert().Equal(ContentTypeJwt, w.Header().Get("Content-Type"))
+
+	claims := s.verify(w.Body.String())
+	s.Assert().Equal("test", claims["iss"])
+	s.Assert().Equal("john@test.org", claims["email"])
+	s.Assert().Nil(claims["name"])
+}
+
+func (s *UserInfoHandlerTestSuite) TestSignedAndEncryptedJwt() {
+	token := s.issue(&userInfoHandlerTestSuiteClient{sign: true, encrypt: true, key: s.clientKey}, spi.ScopeOpenId)
+
+	w := s.serve(token)
+	s.Assert().Equal(http.StatusOK, w.Code)
+	s.Assert().Equal(ContentTypeJwt, w.Header().Get("Content-Type"))
+
+	jwe, err := jose.ParseEncrypted(w.Body.String())
+	s.Require().Nil(err)
+	raw, err := jwe.Decrypt(s.clientKey)
+	s.Require().Nil(err)
+
+	claims := s.verify(string(raw))
+	s.Assert().Equal("pairwise user", claims["sub"])
+}
+
+func (s *UserInfoHandlerTestSuite) TestClaimsRequest() {
+	claimsRequest, err := ParseClaimsRequest(`{"userinfo":{"email":{"essential":true}}}`)
+	s.Require().Nil(err)
+
+	token := s.issueWith(&userInfoHandlerTestSuiteClient{}, func(session Session) {
+		session.SetClaimsRequest(claimsRequest)
+		session.AddClaimsLocales("ja-Kana-JP")
+	}, spi.ScopeOpenId, spi.ScopeProfile)
+
+	w := s.serve(token)
+	s.Assert().Equal(http.StatusOK, w.Code)
+
+	body := make(map[string]interface{})
+	s.Require().Nil(json.NewDecoder(w.Body).Decode(&body))
+	s.Assert().Equal("ジョン", body["name#ja-Kana-JP"])
+	s.Assert().Nil(body["name"])
+	s.Assert().Equal("john@test.org", body["email"])
+}
+
+func (s *UserInfoHandlerTestSuite) TestPairwiseSubject() {
+	s.handler.SubjectObfuscator = &SubjectObfuscator{Salt: []byte("salt")}
+	defer func() { s.handler.SubjectObfuscator = nil }()
+
+	token := s.issue(&userInfoHandlerTestSuiteClient{pairwise: true}, spi.ScopeOpenId)
+
+	w := s.serve(token)
+	s.Assert().Equal(http.StatusOK, w.Code)
+
+	body := make(map[string]interface{})
+	s.Require().Nil(json.NewDecoder(w.Body).Decode(&body))
+	sum := sha256.Sum256([]byte("a.com" + "test user" + "salt"))
+	s.Assert().Equal(base64.RawURLEncoding.EncodeToString(sum[:]), body["sub"])
+
+	stored, err := s.handler.AccessTokenRepo.GetRequest(context.Background(), token)
+	s.Require().Nil(err)
+	s.Assert().Equal("pairwise user", stored.GetSession().(Session).GetObfuscatedSubject())
+}
+
+func (s *UserInfoHandlerTestSuite) TestMissingOpenIdScope() {
+	token := s.issue(&userInfoHandlerTestSuiteClient{}, spi.ScopeProfile)
+
+	w := s.serve(token)
+	s.Assert().Equal(http.StatusForbidden, w.Code)
+	s.Assert().Contains(w.Header().Get("WWW-Authenticate"), "insufficient_scope")
+}
+
+func (s *UserInfoHandlerTestSuite) TestInvalidToken() {
+	w := s.serve("invalid")
+	s.Assert().Equal(http.StatusUnauthorized, w.Code)
+	s.Assert().Contains(w.Header().Get("WWW-Authenticate"), "invalid_token")
+}
+
+func (s *UserInfoHandlerTestSuite) TestRepositoryFailure() {
+	token := s.issue(&userInfoHandlerTestSuiteClient{}, spi.ScopeOpenId)
+	s.handler.AccessTokenRepo = &userInfoHandlerTestSuiteFailingRepo{}
+
+	w := s.serve(token)
+	s.Assert().Equal(http.StatusInternalServerError, w.Code)
+	s.Assert().Empty(w.Header().Get("WWW-Authenticate"))
+}
+
+func (s *UserInfoHandlerTestSuite) TestKeyRotation() {
+	manager, err := oauth.NewKeyManager(oauth.NewRsaKeyGenerator(jose.RS256, 2048), time.Hour, time.Hour)
+	s.Require().Nil(err)
+	s.handler.KeySource = manager
+
+	kids := make([]string, 0)
+	for i := 0; i < 2; i++ {
+		w := s.serve(s.issue(&userInfoHandlerTestSuiteClient{sign: true}, spi.ScopeOpenId))
+		s.Require().Equal(http.StatusOK, w.Code)
+
+		tok, err := jwt.ParseSigned(w.Body.String())
+		s.Require().Nil(err)
+
+		// signed with the active key, which is published
+		kid := tok.Headers[0].KeyID
+		s.Assert().Equal(manager.SigningKey(string(jose.RS256)).KeyID, kid)
+		s.Assert().NotEmpty(oauth.PublishedJwks(manager, s.handler.Jwks).Key(kid))
+		claims := make(map[string]interface{})
+		s.Require().Nil(tok.Claims(manager.VerificationKey(kid), &claims))
+		s.Assert().NotEmpty(claims["sub"])
+
+		kids = append(kids, kid)
+		s.Require().Nil(manager.Rotate())
+	}
+	s.Assert().NotEqual(kids[0], kids[1])
+}
+
+func (s *UserInfoHandlerTestSuite) issue(client spi.OidcClient, scopes ...string) string {
+	return s.issueWith(client, func(session Session) {}, scopes...)
+}
+
+func (s *UserInfoHandlerTestSuite) issueWith(client spi.OidcClient, setup func(session Session), scopes ...string) string {
+	req := NewTokenRequest()
+	req.SetClient(client)
+	req.GetSession().SetSubject("test user")
+	req.GetSession().(Session).SetObfuscatedSubject("pairwise user")
+	req.GetSession().AddGrantedScopes(scopes...)
+	setup(req.GetSession().(Session))
+
+	resp := oauth.NewResponse()
+	s.Require().Nil(s.accessHelper.GenToken(context.Background(), req, resp))
+	return resp.GetString(oauth.AccessToken)
+}
+
+func (s *UserInfoHandlerTestSuite) serve(token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/userinfo", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, r)
+	return w
+}
+
+func (s *UserInfoHandlerTestSuite) verify(raw string) map[string]interface{} {
+	tok, err := jwt.ParseSigned(raw)
+	s.Require().Nil(err)
+
+	claims := make(map[string]interface{})
+	s.Require().Nil(tok.Claims(&s.serverKey.PublicKey, &claims))
+	return claims
+}
+
+// support: oauth.AccessTokenRepository
+type userInfoHandlerTestSuiteAccessTokenRepo struct {
+	*oauth.NoOpAccessTokenRepo
+	sync.Mutex
+	store map[string]oauth.Request
+}
+
+func (r *userInfoHandlerTestSuiteAccessTokenRepo) Save(ctx context.Context, token string, req oauth.Request) error {
+	r.Lock()
+	defer r.Unlock()
+	r.store[token] = req
+	return nil
+}
+
+func (r *userInfoHandlerTestSuiteAccessTokenRepo) GetRequest(ctx context.Context, token string) (oauth.Request, error) {
+	r.Lock()
+	defer r.Unlock()
+	if req, ok := r.store[token]; ok {
+		return req, nil
+	}
+	return nil, spi.ErrInvalidToken("not found")
+}
+
+// support: oauth.AccessTokenRepository whose storage is unavailable
+type userInfoHandlerTestSuiteFailingRepo struct {
+	*oauth.NoOpAccessTokenRepo
+}
+
+func (r *userInfoHandlerTestSuiteFailingRepo) GetRequest(ctx context.Context, token string) (oauth.Request, error) {
+	return nil, errors.New("connection refused")
+}
+
+// support: spi.ClaimsProvider
+type userInfoHandlerTestSuiteClaimsProvider struct{}
+
+func (p *userInfoHandlerTestSuiteClaimsProvider) GetClaims(ctx context.Context, subject string) (map[string]interface{}, error) {
+	if subject != "test user" {
+		return nil, errors.New("unknown subject")
+	}
+	return map[string]interface{}{
+		"name":            "John Doe",
+		"name#ja-Kana-JP": "ジョン",
+		"email":           "john@test.org",
+	}, nil
+}
+
+// support: OidcClient with userinfo registration
+type userInfoHandlerTestSuiteClient struct {
+	*test.PanicClient
+	sign     bool
+	encrypt  bool
+	pairwise bool
+	key      *rsa.PrivateKey
+}
+
+func (c *userInfoHandlerTestSuiteClient) GetSubjectType() string {
+	if c.pairwise {
+		return SubjectTypePairwise
+	}
+	return SubjectTypePublic
+}
+
+func (c *userInfoHandlerTestSuiteClient) GetSectorIdentifierUri() string {
+	return ""
+}
+
+func (c *userInfoHandlerTestSuiteClient) GetRedirectUris() []string {
+	return []string{"https://a.com/cb"}
+}
+
+func (c *userInfoHandlerTestSuiteClient) GetId() string {
+	return "9a1f5b9e-7c55-4b8c-a0ad-3c4c0f1d7e26"
+}
+
+func (c *userInfoHandlerTestSuiteClient) GetJwks() string {
+	jwks, err := json.Marshal(&jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{Algorithm: spi.EncryptAlgRSAOAEP, KeyID: "enc-key", Key: &c.key.PublicKey, Use: "enc"},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return string(jwks)
+}
+
+func (c *userInfoHandlerTestSuiteClient) GetUserInfoSignedResponseAlg() string {
+	if c.sign {
+		return spi.SignAlgRS256
+	}
+	return ""
+}
+
+func (c *userInfoHandlerTestSuiteClient) GetUserInfoEncryptedResponseAlg() string {
+	if c.encrypt {
+		return spi.EncryptAlgRSAOAEP
+	}
+	return ""
+}
+
+func (c *userInfoHandlerTestSuiteClient) GetUserInfoEncryptedResponseEnc() string {
+	if c.encrypt {
+		return spi.EncAlgA128GCM
+	}
+	return ""
+}
@@ -41,16 +41,22 @@ func (v *AuthorizeRequestValidator) Validate(ctx context.Context, req oauth.Requ
 
 func (v *AuthorizeRequestValidator) validateResponseMode(authReq AuthorizeRequest) error {
 	if len(authReq.GetResponseMode()) > 0 {
-		if !oauth.V(v.supportedResponseModes()).Contains(authReq.GetResponseMode()) {
+		if !oauth.V(v.SupportedResponseModes()).Contains(authReq.GetResponseMode()) {
 			return spi.ErrInvalidRequest("invalid response_mode value")
 		}
+		// tokens must not leak through the query component unless the response is encrypted.
+		if authReq.GetResponseMode() == spi.ResponseModeQueryJwt &&
+			!oauth.V(authReq.GetResponseTypes()).ContainsExactly(spi.ResponseTypeCode) &&
+			!isJarmEncrypted(authReq.GetClient()) {
+			return spi.ErrInvalidRequest("query.jwt response_mode requires encryption for response types other than code")
+		}
 	}
 	return nil
 }
 
 func (v *AuthorizeRequestValidator) validateDisplay(authReq AuthorizeRequest) error {
 	if len(authReq.GetDisplay()) > 0 {
-		if !oauth.V(v.supportedDisplayValues()).Contains(authReq.GetDisplay()) {
+		if !oauth.V(v.SupportedDisplayValues()).Contains(authReq.GetDisplay()) {
 			return spi.ErrInvalidRequest("invalid display value")
 		}
 	}
@@ -81,17 +87,24 @@ func (v *AuthorizeRequestValidator) validatePrompts(authReq AuthorizeRequest) er
 	return nil
 }
 
-func (v *AuthorizeRequestValidator) supportedResponseModes() []string {
+// Returns the response modes accepted by the validator.
+func (v *AuthorizeRequestValidator) SupportedResponseModes() []string {
 	if len(v.ResponseModesOverride) > 0 {
 		return v.ResponseModesOverride
 	}
 	return []string{
 		spi.ResponseModeQuery,
 		spi.ResponseModeFragment,
+		spi.ResponseModeFormPost,
+		spi.ResponseModeJwt,
+		spi.ResponseModeQueryJwt,
+		spi.ResponseModeFragmentJwt,
+		spi.ResponseModeFormPostJwt,
 	}
 }
 
-func (v *AuthorizeRequestValidator) supportedDisplayValues() []string {
+// Returns the display values accepted by the validator.
+func (v *AuthorizeRequestValidator) SupportedDisplayValues() []string {
 	if len(v.DisplayValuesOverride) > 0 {
 		return v.DisplayValuesOverride
 	}
@@ -105,4 +118,12 @@ func (v *AuthorizeRequestValidator) supportedDisplayValues() []string {
 
 type TokenRequestValidator struct {
 	*oauth.TokenRequestValidator
-}
\ No newline at end of file
+}
+
+func isJarmEncrypted(client spi.OAuthClient) bool {
+	if jarm, ok := client.(spi.JarmAware); ok {
+		return len(jarm.GetAuthorizationEncryptedResponseAlg()) > 0 &&
+			jarm.GetAuthorizationEncryptedResponseAlg() != spi.EncryptAlgNone
+	}
+	return false
+}
@@ -65,6 +65,39 @@ func TestAuthorizeRequestValidator_Validate(t *testing.T) {
 			},
 			expectsError: true,
 		},
+		{
+			name: "form_post response_mode",
+			reqFunc: func() AuthorizeRequest {
+				req := NewAuthorizeRequest()
+				req.SetClient(new(validationTestClient))
+				req.AddResponseTypes(spi.ResponseTypeCode)
+				req.SetResponseMode(spi.ResponseModeFormPost)
+				return req
+			},
+			expectsError: false,
+		},
+		{
+			name: "query.jwt response_mode for code",
+			reqFunc: func() AuthorizeRequest {
+				req := NewAuthorizeRequest()
+				req.SetClient(new(validationTestClient))
+				req.AddResponseTypes(spi.ResponseTypeCode)
+				req.SetResponseMode(spi.ResponseModeQueryJwt)
+				return req
+			},
+			expectsError: false,
+		},
+		{
+			name: "unencrypted query.jwt response_mode for token",
+			reqFunc: func() AuthorizeRequest {
+				req := NewAuthorizeRequest()
+				req.SetClient(new(validationTestClient))
+				req.AddResponseTypes(spi.ResponseTypeToken)
+				req.SetResponseMode(spi.ResponseModeQueryJwt)
+				return req
+			},
+			expectsError: true,
+		},
 	}{
 		err := validator.Validate(context.Background(), v.reqFunc())
 		if v.expectsError {
@@ -81,9 +114,9 @@ type validationTestClient struct {
 }
 
 func (c *validationTestClient) GetResponseTypes() []string {
-	return []string{spi.ResponseTypeCode}
+	return []string{spi.ResponseTypeCode, spi.ResponseTypeToken}
 }
 
 func (c *validationTestClient) GetGrantTypes() []string {
-	return []string{spi.GrantTypeCode}
+	return []string{spi.GrantTypeCode, spi.GrantTypeImplicit}
 }
\ No newline at end of file
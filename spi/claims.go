@@ -0,0 +1,10 @@
+package spi
+
+import "context"
+
+type ClaimsProvider interface {
+	// Returns the claims of the end-user identified by the subject, keyed by the standard claim names defined in
+	// Open ID Connect Core 1.0 Section 5.1 (i.e. name, email, address). Claims not available shall simply be omitted.
+	// Implementations are encouraged to return OAuthError typed error.
+	GetClaims(ctx context.Context, subject string) (map[string]interface{}, error)
+}
@@ -30,6 +30,21 @@ type ClientSecretAware interface {
 	GetSecret() string
 }
 
+// Add-on interface for OidcClient to implement if it registered for JWT Secured Authorization Response Mode (JARM).
+// Clients that do not implement this interface receive authorization responses signed with RS256 and not encrypted.
+type JarmAware interface {
+	// authorization_signed_response_alg
+	// Optional. The JWS 'alg' header value for signing authorization responses. By default, this should be set to RS256.
+	// 'none' MUST NOT be used.
+	GetAuthorizationSignedResponseAlg() string
+	// authorization_encrypted_response_alg
+	// Optional. The JWE 'alg' header value. If omitted or 'none', authorization responses will only be signed.
+	GetAuthorizationEncryptedResponseAlg() string
+	// authorization_encrypted_response_enc
+	// Optional. The JWE 'enc' header value. By default, this value is A128CBC-HS256.
+	GetAuthorizationEncryptedResponseEnc() string
+}
+
 type OidcClient interface {
 	OAuthClient
 	// application_type
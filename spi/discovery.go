@@ -36,4 +36,6 @@ type Discovery struct {
 	RequireRequestUriRegistration			bool 		`json:"require_request_uri_registration"`
 	OpPolicyUri								string		`json:"op_policy_uri"`
 	OpTosUri								string 		`json:"op_tos_uri"`
+	PushedAuthorizationRequestEndpoint		string		`json:"pushed_authorization_request_endpoint,omitempty"`
+	RequirePushedAuthorizationRequests		bool		`json:"require_pushed_authorization_requests,omitempty"`
 }
\ No newline at end of file
@@ -146,6 +146,15 @@ func ErrServerErrorf(msg string, args ...interface{}) *OAuthError {
 	return ErrServerError(fmt.Errorf(msg, args...))
 }
 
+// Convenience function to convert any error to OAuthError. If the error is already an OAuthError, it is returned as is;
+// otherwise, it is treated as a server_error.
+func AsOAuthError(err error) *OAuthError {
+	if oauthErr, ok := err.(*OAuthError); ok {
+		return oauthErr
+	}
+	return ErrServerError(err)
+}
+
 // Factory method to create a temporarily_unavailable error.
 // The authorization server is currently unable to handle
 // the request due to a temporary overloading or maintenance
@@ -159,3 +168,77 @@ func ErrTemporarilyUnavailable() *OAuthError {
 		Code: 503,
 	}
 }
+
+// Factory method to create an invalid_token error (RFC 6750).
+// This error should be raised when the access token provided is
+// expired, revoked, malformed, or invalid for other reasons. The
+// WWW-Authenticate header is set with the Bearer scheme.
+func ErrInvalidToken(reason string) *OAuthError {
+	return &OAuthError{
+		Err: "invalid_token",
+		Reason: reason,
+		Code: 401,
+		Headers: map[string]string{
+			"WWW-Authenticate": fmt.Sprintf("Bearer error=\"invalid_token\" error_description=\"%s\"", reason),
+		},
+	}
+}
+
+// Factory method to create an insufficient_scope error (RFC 6750).
+// This error should be raised when the request requires higher
+// privileges than provided by the access token. The WWW-Authenticate
+// header is set with the Bearer scheme and the required scope.
+func ErrInsufficientScope(reason string, scope string) *OAuthError {
+	return &OAuthError{
+		Err: "insufficient_scope",
+		Reason: reason,
+		Code: 403,
+		Headers: map[string]string{
+			"WWW-Authenticate": fmt.Sprintf("Bearer error=\"insufficient_scope\" scope=\"%s\"", scope),
+		},
+	}
+}
+
+// Factory method to create an invalid_request_object error (Open ID Connect Core 1.0 Section 3.1.2.6).
+// This error should be raised when the request parameter contains
+// an invalid request object.
+func ErrInvalidRequestObject(reason string) *OAuthError {
+	return &OAuthError{
+		Err: "invalid_request_object",
+		Reason: reason,
+		Code: 400,
+	}
+}
+
+// Factory method to create an invalid_request_uri error (Open ID Connect Core 1.0 Section 3.1.2.6).
+// This error should be raised when the request_uri in the authorization
+// request returns an error or contains invalid data.
+func ErrInvalidRequestUri(reason string) *OAuthError {
+	return &OAuthError{
+		Err: "invalid_request_uri",
+		Reason: reason,
+		Code: 400,
+	}
+}
+
+// Factory method to create a request_not_supported error (Open ID Connect Core 1.0 Section 3.1.2.6).
+// This error should be raised when the server does not support
+// use of the request parameter.
+func ErrRequestNotSupported() *OAuthError {
+	return &OAuthError{
+		Err: "request_not_supported",
+		Reason: "request parameter is not supported.",
+		Code: 400,
+	}
+}
+
+// Factory method to create a request_uri_not_supported error (Open ID Connect Core 1.0 Section 3.1.2.6).
+// This error should be raised when the server does not support
+// use of the request_uri parameter.
+func ErrRequestUriNotSupported() *OAuthError {
+	return &OAuthError{
+		Err: "request_uri_not_supported",
+		Reason: "request_uri parameter is not supported.",
+		Code: 400,
+	}
+}
@@ -0,0 +1,17 @@
+package spi
+
+import (
+	"context"
+	"errors"
+)
+
+// Returned by ResourceOwnerAuthenticator when the supplied credentials are invalid.
+var ErrBadCredentials = errors.New("invalid resource owner credentials")
+
+type ResourceOwnerAuthenticator interface {
+	// Authenticate the resource owner with the username and password supplied through the password grant. Returns
+	// the subject identifier of the resource owner when the credentials are valid; otherwise returns an error.
+	// Invalid credentials must be reported with ErrBadCredentials, or an OAuthError typed error. Any other error is
+	// deemed a failure of the authenticator itself and treated as server_error.
+	Authenticate(ctx context.Context, username string, password string) (string, error)
+}
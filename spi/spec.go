@@ -20,6 +20,10 @@ const (
 const (
 	ScopeOpenId        = "openid"
 	ScopeOfflineAccess = "offline_access"
+	ScopeProfile       = "profile"
+	ScopeEmail         = "email"
+	ScopeAddress       = "address"
+	ScopePhone         = "phone"
 )
 
 // client_type
@@ -44,6 +48,12 @@ const (
 const (
 	ResponseModeQuery    = "query"
 	ResponseModeFragment = "fragment"
+	ResponseModeFormPost = "form_post"
+	// JWT Secured Authorization Response Mode (JARM)
+	ResponseModeJwt         = "jwt"
+	ResponseModeQueryJwt    = "query.jwt"
+	ResponseModeFragmentJwt = "fragment.jwt"
+	ResponseModeFormPostJwt = "form_post.jwt"
 )
 
 // display
@@ -131,6 +141,43 @@ const (
 	ParamRedirectUri         = "redirect_uri"
 	ParamScope               = "scope"
 	ParamState               = "state"
+	ParamGrantType           = "grant_type"
+	ParamCode                = "code"
+	ParamRefreshToken        = "refresh_token"
+	ParamCodeChallenge       = "code_challenge"
+	ParamCodeChallengeMethod = "code_challenge_method"
+	ParamCodeVerifier        = "code_verifier"
+	ParamUsername            = "username"
+	ParamPassword            = "password"
+	ParamToken               = "token"
+	ParamTokenTypeHint       = "token_type_hint"
+	ParamRequest             = "request"
+	ParamRequestUri          = "request_uri"
+	ParamResponseMode        = "response_mode"
+	ParamNonce               = "nonce"
+	ParamDisplay             = "display"
+	ParamPrompt              = "prompt"
+	ParamMaxAge              = "max_age"
+	ParamUiLocales           = "ui_locales"
+	ParamIdTokenHint         = "id_token_hint"
+	ParamLoginHint           = "login_hint"
+	ParamAcrValues           = "acr_values"
+	ParamClaims              = "claims"
+	ParamClaimsLocales       = "claims_locales"
+	ParamIss                 = "iss"
+	ParamTargetLinkUri       = "target_link_uri"
+)
+
+// token_type_hint
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// code_challenge_method
+const (
+	CodeChallengeMethodPlain = "plain"
+	CodeChallengeMethodS256  = "S256"
 )
 
 // Misc
@@ -0,0 +1,41 @@
+package spi
+
+import "context"
+
+// A unit of work spanning the persistence operations performed while handling a single request, such as saving the
+// authorization code, saving the issued tokens and deleting the used authorization code. Implementations typically
+// wrap a database transaction.
+type Transaction interface {
+	// Make all operations performed within the unit of work permanent.
+	Commit() error
+	// Discard all operations performed within the unit of work.
+	Rollback() error
+}
+
+// Hook for starting a Transaction. Repositories enlist in the transaction by retrieving it from the context with
+// TransactionFrom.
+type TransactionManager interface {
+	// Begin a new transaction. The returned context carries the transaction and must be used for all operations which
+	// should take part in it.
+	Begin(ctx context.Context) (context.Context, Transaction, error)
+}
+
+type transactionKey struct{}
+
+// Returns a copy of the context carrying the transaction. A nil transaction detaches the returned context from any
+// transaction carried by the parent.
+func WithTransaction(ctx context.Context, tx Transaction) context.Context {
+	return context.WithValue(ctx, transactionKey{}, tx)
+}
+
+// Returns a copy of the context which carries no transaction. Operations which must persist even when the unit of work
+// is rolled back, like revoking tokens upon detection of a replay, should be performed with this context.
+func WithoutTransaction(ctx context.Context) context.Context {
+	return WithTransaction(ctx, nil)
+}
+
+// Returns the transaction carried by the context, if any.
+func TransactionFrom(ctx context.Context) (Transaction, bool) {
+	tx, ok := ctx.Value(transactionKey{}).(Transaction)
+	return tx, ok && tx != nil
+}